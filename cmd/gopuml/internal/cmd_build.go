@@ -1,16 +1,26 @@
 package internal
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"html"
 	"io"
-	"net/http"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/internal/config"
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+	"github.com/lonnblad/gopuml/internal/render"
+	"github.com/lonnblad/gopuml/internal/source"
 )
 
 const (
@@ -21,28 +31,56 @@ const (
 	flagStyle                   = "style"
 	flagFormat, flagShortFormat = "format", "f"
 	flagServer                  = "server"
-
-	styleFile = "file"
-	styleLink = "link"
-	styleOut  = "out"
+	flagErrorFormat             = "error-format"
+	flagQuiet, flagShortQuiet   = "quiet", "q"
+	flagTarget                  = "target"
+	flagReport                  = "report"
+
+	styleFile     = "file"
+	styleLink     = "link"
+	styleOut      = "out"
+	styleJSON     = "json"
+	styleMarkdown = "markdown"
+	styleHTML     = "html"
+	styleAsciiDoc = "asciidoc"
+	styleDataURI  = "datauri"
 
 	formatPNG = "png"
 	formatSVG = "svg"
 	formatTXT = "txt"
+
+	stdinFilename = "<stdin>"
 )
 
 type buildOptions struct {
-	Server string
-	Style  string
-	Format string
+	Server      string
+	Style       string
+	Format      string
+	ErrorFormat string
+	Quiet       bool
+	Target      string
+	Report      string
+
+	ChangedSince string
+	Staged       bool
+	Check        bool
+
+	Client clientOptions
 }
 
 const flagUsageStyle = `the style in which to compile the files
 
 supported styles are:
-  ` + styleFile + `  will write the formatted content to a file
-  ` + styleLink + `  will write a link to the formatted content to stdout
-  ` + styleOut + `   will write the formatted content to stdout
+  ` + styleFile + `      will write the formatted content to a file
+  ` + styleLink + `      will write a link to the formatted content to stdout
+  ` + styleOut + `       will write the formatted content to stdout
+  ` + styleJSON + `      will write the build report to stdout
+  ` + styleMarkdown + `  will write a Markdown image with a link to the formatted content to stdout
+  ` + styleHTML + `      will write an HTML img tag with a link to the formatted content to stdout
+  ` + styleAsciiDoc + `  will write an AsciiDoc image macro with a link to the formatted content to stdout
+  ` + styleDataURI + `   will write the formatted content as a base64 encoded data URI to stdout
+
+the markdown, html and asciidoc styles use the title or the name of the diagram as the alt text
  `
 
 const flagUsageFormat = `the format of the compiled files
@@ -53,131 +91,557 @@ supported formatters are:
   ` + formatTXT + `  will format the content as .txt
  `
 
+const flagUsageErrorFormat = `the format in which to report diagrams that fail to render
+
+supported formats are:
+  ` + diagnostic.FormatText + `  will write one "<file>:<line>: <message>" per line to stderr
+  ` + diagnostic.FormatJSON + `  will write a JSON array to stderr
+ `
+
+const flagUsageQuiet = `don't print the files that are written, only errors,
+suited for go:generate directives
+ `
+
+const flagUsageTarget = `the target in the configuration file to build,
+instead of the files in the arguments
+ `
+
 const flagUsageServer = `the Server URL to use when the style used is link,
 
 the provided server need to support links formatted like:
   "<server_url>/<format>/<plant_uml_text_encoding>"
  `
 
+const flagUsageReport = `the file to write a JSON report of the build to, with the input, output, link,
+size, hash, render duration and error of every diagram, it's written when the build fails as well
+ `
+
+const flagUsageBuildCheck = `check that the files are up to date without writing them,
+fails if any file would change, only supported with the file style
+ `
+
+// buildConfigFlags are the flags of build which are set from the environment or the configuration.
+var buildConfigFlags = append([]string{
+	flagStyle, flagFormat, flagServer, flagErrorFormat, flagQuiet, flagTarget, flagReport, flagCheck, flagStaged, flagChangedSince,
+}, clientFlags...)
+
 // CreateBuildCmd creates the build subcommand.
 func CreateBuildCmd() cobra.Command {
 	opts := buildOptions{
 		Server: defaultServer,
 		Style:  defaultStyle,
 		Format: defaultFormat,
+
+		ErrorFormat: diagnostic.FormatText,
 	}
 
 	buildCmd := cobra.Command{
 		Use:   "build [plant UML files]",
 		Short: "Compiles Plant UML files",
+		Long: `Compiles Plant UML files.
+
+With the file style, the files are only written when their content changes,
+and they are replaced atomically, so they are never left partially written.
+
+The defaults for the flags are read from environment variables, like GOPUML_SERVER,
+and from the configuration file, ` + config.Filename + `, in that order.
+The configuration file can also define targets, which are sets of files to build.`,
 		Example: `  gopuml build example.puml
-  gopuml build -f png --style link example.puml`,
+  gopuml build -f png --style link example.puml
+  gopuml build --target docs
+  gopuml build --changed-since origin/main
+  //go:generate gopuml build --quiet example.puml`,
 		RunE: buildCmdRunFunc(&opts),
 	}
 
 	buildCmd.Flags().StringVarP(&opts.Format, flagFormat, flagShortFormat, opts.Format, flagUsageFormat)
 	buildCmd.Flags().StringVar(&opts.Server, flagServer, opts.Server, flagUsageServer)
 	buildCmd.Flags().StringVar(&opts.Style, flagStyle, opts.Style, flagUsageStyle)
+	buildCmd.Flags().StringVar(&opts.ErrorFormat, flagErrorFormat, opts.ErrorFormat, flagUsageErrorFormat)
+	buildCmd.Flags().BoolVarP(&opts.Quiet, flagQuiet, flagShortQuiet, opts.Quiet, flagUsageQuiet)
+	buildCmd.Flags().StringVar(&opts.Target, flagTarget, opts.Target, flagUsageTarget)
+	buildCmd.Flags().StringVar(&opts.Report, flagReport, opts.Report, flagUsageReport)
+	buildCmd.Flags().StringVar(&opts.ChangedSince, flagChangedSince, opts.ChangedSince, flagUsageChangedSince)
+	buildCmd.Flags().BoolVar(&opts.Staged, flagStaged, opts.Staged, flagUsageStaged)
+	buildCmd.Flags().BoolVar(&opts.Check, flagCheck, opts.Check, flagUsageBuildCheck)
+	addClientFlags(buildCmd.Flags(), &opts.Client)
+	addConfigFlag(&buildCmd)
 
 	return buildCmd
 }
 
 func buildCmdRunFunc(opts *buildOptions) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return buildFromStdIn(opts, cmd)
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
 		}
 
-		return buildFromArgs(opts, cmd, args)
+		if err = applyDefaults(cmd, cfg, buildConfigFlags...); err != nil {
+			return err
+		}
+
+		switch opts.Style {
+		case styleFile, styleLink, styleOut, styleJSON, styleMarkdown, styleHTML, styleAsciiDoc, styleDataURI:
+		default:
+			return fmt.Errorf("unsupported style: [%s]", opts.Style)
+		}
+
+		if opts.Check && opts.Style != styleFile {
+			return fmt.Errorf("--%s is only supported with the %s style", flagCheck, styleFile)
+		}
+
+		if opts.ErrorFormat != diagnostic.FormatText && opts.ErrorFormat != diagnostic.FormatJSON {
+			return fmt.Errorf("unsupported error format: [%s]", opts.ErrorFormat)
+		}
+
+		renderer, err := newRenderer(opts.Server, opts.Client)
+		if err != nil {
+			return err
+		}
+
+		b := builder{
+			opts:     *opts,
+			cmd:      cmd,
+			renderer: renderer,
+			formats:  []string{opts.Format},
+		}
+
+		switch {
+		case opts.Target != "" && len(args) > 0:
+			return fmt.Errorf("can't build both the target [%s] and files", opts.Target)
+		case opts.Target != "":
+			err = b.buildTarget(cfg)
+		case len(args) == 0 && opts.ChangedSince == "" && opts.Staged:
+			return fmt.Errorf("--%s can't be used with stdin", flagStaged)
+		case len(args) == 0 && opts.ChangedSince == "":
+			err = b.buildFromStdIn()
+		default:
+			err = b.buildFromArgs(args)
+		}
+
+		// The report is written when the build fails as well, with the error of the diagram it failed on.
+		if reportErr := b.writeReport(); err == nil {
+			err = reportErr
+		}
+
+		if err != nil {
+			return interrupted(cmd, err)
+		}
+
+		return b.reportDiagnostics()
 	}
 }
 
-func buildFromStdIn(opts *buildOptions, cmd *cobra.Command) error {
-	content, err := io.ReadAll(cmd.InOrStdin())
+// builder builds Plant UML files and collects the diagnostics for the diagrams that fail to render.
+type builder struct {
+	opts     buildOptions
+	cmd      *cobra.Command
+	renderer *render.Renderer
+
+	// formats are the formats to build every file in.
+	formats []string
+	// outDir is the directory to write files to, files are written next to the source if it's empty.
+	outDir string
+	// outputNames are the paths of the outputs relative to the out dir, without extension, by source file.
+	outputNames map[string]string
+
+	diagnostics []diagnostic.Diagnostic
+	// outOfDate are the files which would change, in check mode.
+	outOfDate []string
+
+	// report has an entry for every diagram built in every format.
+	report []*reportEntry
+	// cache has the rendered output by format and encoded content.
+	cache map[string][]byte
+}
+
+// buildTarget builds the files of the target in the configuration,
+// in the formats and to the directory of the target.
+func (b *builder) buildTarget(cfg config.Config) error {
+	target, err := cfg.Target(b.opts.Target)
 	if err != nil {
 		return err
 	}
 
-	if content, err = compressAndEncode(content); err != nil {
+	files, err := config.Glob(cfg.Dir(), target.Inputs)
+	if err != nil {
 		return err
 	}
 
-	if err = opts.writeOutput(cmd.OutOrStdout(), content); err != nil {
+	if len(files) == 0 {
+		return fmt.Errorf("no files found for the target [%s]", b.opts.Target)
+	}
+
+	if len(target.Formats) > 0 {
+		b.formats = target.Formats
+	}
+
+	if target.OutDir != "" {
+		b.outDir = target.OutDir
+		if !filepath.IsAbs(b.outDir) {
+			b.outDir = filepath.Join(cfg.Dir(), b.outDir)
+		}
+
+		if b.outputNames, err = outputNames(cfg.Dir(), target.Inputs, b.outDir); err != nil {
+			return err
+		}
+	}
+
+	return b.buildFromArgs(files)
+}
+
+// outputNames returns the paths of the outputs of the files matching the patterns relative to the out dir,
+// without extension. A file keeps its path relative to the directory before the first wildcard of the first
+// pattern matching it, and files whose outputs would overwrite each other are reported as an error.
+func outputNames(dir string, patterns []string, outDir string) (map[string]string, error) {
+	names := make(map[string]string)
+	files := make(map[string]string)
+
+	for _, pattern := range patterns {
+		root := config.GlobRoot(dir, pattern)
+
+		matches, err := config.Glob(dir, []string{pattern})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range matches {
+			if _, ok := names[file]; ok {
+				continue
+			}
+
+			relativePath, err := filepath.Rel(root, file)
+			if err != nil {
+				return nil, err
+			}
+
+			name := strings.TrimSuffix(relativePath, filepath.Ext(relativePath))
+
+			if other, ok := files[name]; ok {
+				return nil, fmt.Errorf("the outputs of [%s] and [%s] would both be written to [%s]",
+					displayPath(other), displayPath(file), displayPath(filepath.Join(outDir, name)))
+			}
+
+			names[file], files[name] = name, file
+		}
+	}
+
+	return names, nil
+}
+
+func (b *builder) buildFromStdIn() error {
+	content, err := io.ReadAll(b.cmd.InOrStdin())
+	if err != nil {
 		return err
 	}
 
-	return nil
+	src := source.New(stdinFilename, content)
+
+	_, err = b.build(b.cmd.OutOrStdout(), stdinFilename, b.opts.Format, src)
+
+	return err
 }
 
-func buildFromArgs(opts *buildOptions, cmd *cobra.Command, args []string) error {
+func (b *builder) buildFromArgs(args []string) error {
 	filepaths, err := findAbsolutePaths(args)
 	if err != nil {
 		return err
 	}
 
+	if b.opts.ChangedSince != "" {
+		if filepaths, err = changedFiles(b.cmd, b.opts.ChangedSince, filepaths); err != nil {
+			return err
+		}
+	}
+
 	for _, file := range filepaths {
-		content, err := os.ReadFile(file)
-		if err != nil {
+		if err = b.cmd.Context().Err(); err != nil {
 			return err
 		}
 
-		if content, err = compressAndEncode(content); err != nil {
+		src, err := source.LoadWith(file, readFunc(b.opts.Staged))
+		if err != nil {
 			return err
 		}
 
-		output := cmd.OutOrStdout()
+		for _, format := range b.formats {
+			if b.opts.Style != styleFile {
+				_, err = b.build(b.cmd.OutOrStdout(), file, format, src)
+			} else {
+				err = b.buildFile(file, format, src)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
 
-		if opts.Style == styleFile {
-			outputFilename := strings.TrimSuffix(file, filepath.Ext(file))
-			outputFilename = fmt.Sprintf("%s.%s", outputFilename, opts.Format)
+// buildFile writes the output for the source to a file next to it, or in the out dir,
+// the file is only written when its content changes.
+func (b *builder) buildFile(file, format string, src source.Source) error {
+	noOfDiagnostics := len(b.diagnostics)
 
-			var f *os.File
+	var output bytes.Buffer
 
-			const readWriteMode = 0600
-			if f, err = os.OpenFile(outputFilename, os.O_RDWR|os.O_CREATE, readWriteMode); err != nil {
-				return fmt.Errorf("couldn't open file: %w", err)
-			}
+	entry, err := b.build(&output, file, format, src)
+	if err != nil {
+		return err
+	}
 
-			defer f.Close()
+	// The file isn't written when the diagram fails to render, or when the build is cancelled.
+	if len(b.diagnostics) > noOfDiagnostics {
+		return nil
+	}
 
-			output = f
-		}
+	if err = b.cmd.Context().Err(); err != nil {
+		entry.Error = err.Error()
+		return err
+	}
 
-		if err = opts.writeOutput(output, content); err != nil {
+	outputFilename := strings.TrimSuffix(file, filepath.Ext(file))
+	outputFilename = fmt.Sprintf("%s.%s", outputFilename, format)
+
+	if b.outDir != "" {
+		outputFilename = filepath.Join(b.outDir, fmt.Sprintf("%s.%s", b.outputNames[file], format))
+	}
+
+	entry.Output = displayPath(outputFilename)
+
+	if b.opts.Check {
+		existing, err := readFunc(b.opts.Staged)(outputFilename)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
+
+		if !bytes.Equal(existing, output.Bytes()) {
+			b.outOfDate = append(b.outOfDate, displayPath(outputFilename))
+		}
+
+		return nil
+	}
+
+	if b.outDir != "" {
+		const readWriteExecuteMode = 0700
+		if err = os.MkdirAll(filepath.Dir(outputFilename), readWriteExecuteMode); err != nil {
+			return fmt.Errorf("couldn't create directory: %w", err)
+		}
+	}
+
+	const readWriteMode = 0600
+
+	written, err := fsutil.WriteFile(outputFilename, output.Bytes(), readWriteMode)
+	if err != nil {
+		entry.Error = fmt.Sprintf("couldn't write file: %v", err)
+		return fmt.Errorf("couldn't write file: %w", err)
+	}
+
+	entry.Written = written
+
+	if written && !b.opts.Quiet {
+		fmt.Fprintln(b.cmd.OutOrStdout(), "updated file:", displayPath(outputFilename))
 	}
 
 	return nil
 }
 
-func (opts buildOptions) writeOutput(out io.Writer, content []byte) error {
-	switch opts.Style {
-	case styleLink:
-		link := createLink(opts.Server, opts.Format, content)
-		fmt.Fprintln(out, link)
-	case styleFile, styleOut:
-		link := createLink(opts.Server, opts.Format, content)
+// build compresses, encodes and writes the output for the source,
+// diagram errors are collected as diagnostics instead of being returned.
+// The returned entry in the build report is completed by the caller.
+func (b *builder) build(out io.Writer, input, format string, src source.Source) (*reportEntry, error) {
+	encoded, err := compressAndEncode(src.Content)
+	if err != nil {
+		return nil, err
+	}
 
-		response, err := http.Get(link) // nolint: gosec
-		if err != nil {
-			return fmt.Errorf("can't fetch output: %w", err)
-		}
+	entry := &reportEntry{
+		Input:  displayPath(input),
+		Name:   source.DiagramName(src.Content),
+		Format: format,
+		Link:   b.renderer.Link(format, encoded),
+	}
 
-		defer response.Body.Close()
+	b.report = append(b.report, entry)
 
-		if response.StatusCode != http.StatusOK {
-			return fmt.Errorf("wrong status code %s, when fetching link: %s", response.Status, link)
-		}
+	switch b.opts.Style {
+	case styleLink, styleMarkdown, styleHTML, styleAsciiDoc:
+		b.warnLinkLength(src, entry.Link)
+		fmt.Fprintln(out, imageReference(b.opts.Style, entry.Link, altText(input, src)))
+
+		return entry, nil
+	}
+
+	content, err := b.render(entry, format, encoded)
+	if diagramErr, ok := render.AsDiagramError(err); ok {
+		entry.Error = diagramErr.Error()
+		b.addDiagnostic(src, diagramErr)
+
+		return entry, nil
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+		return nil, err
+	}
+
+	if err = b.writeOutput(out, format, content); err != nil {
+		entry.Error = err.Error()
+	}
+
+	return entry, err
+}
+
+// render renders the encoded Plant UML, the output is cached for the build,
+// so diagrams with the same content are only rendered once.
+func (b *builder) render(entry *reportEntry, format string, encoded []byte) ([]byte, error) {
+	key := format + "/" + string(encoded)
+
+	if content, ok := b.cache[key]; ok {
+		entry.setContent(content, cacheHit, 0)
+		return content, nil
+	}
+
+	var output bytes.Buffer
+
+	start := time.Now()
 
-		if _, err = io.Copy(out, response.Body); err != nil {
+	if err := b.renderer.Render(b.cmd.Context(), &output, format, encoded); err != nil {
+		return nil, err
+	}
+
+	if b.cache == nil {
+		b.cache = make(map[string][]byte)
+	}
+
+	b.cache[key] = output.Bytes()
+	entry.setContent(output.Bytes(), cacheMiss, time.Since(start))
+
+	return output.Bytes(), nil
+}
+
+func (b *builder) writeOutput(out io.Writer, format string, content []byte) error {
+	switch b.opts.Style {
+	case styleFile, styleOut:
+		if _, err := out.Write(content); err != nil {
 			return fmt.Errorf("couldn't write to output: %w", err)
 		}
+	case styleDataURI:
+		fmt.Fprintf(out, "data:%s;base64,%s\n", mimeTypes[format], base64.StdEncoding.EncodeToString(content))
+	case styleJSON:
+		// The report is written when the build is done.
 	}
 
 	return nil
 }
 
+// mimeTypes are the MIME types of the formats.
+var mimeTypes = map[string]string{
+	formatPNG: "image/png",
+	formatSVG: "image/svg+xml",
+	formatTXT: "text/plain;charset=utf-8",
+}
+
+// imageReference formats a reference to the image in the link in the style,
+// the link itself is returned for the link style.
+func imageReference(style, link, alt string) string {
+	switch style {
+	case styleMarkdown:
+		return fmt.Sprintf("![%s](%s)", strings.NewReplacer("[", `\[`, "]", `\]`).Replace(alt), link)
+	case styleHTML:
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(link), html.EscapeString(alt))
+	case styleAsciiDoc:
+		return fmt.Sprintf(`image::%s["%s"]`, link, strings.ReplaceAll(alt, `"`, `\"`))
+	}
+
+	return link
+}
+
+// altText returns the title of the diagram, or else its name or the name of the input file.
+func altText(input string, src source.Source) string {
+	if title := source.DiagramTitle(src.Content); title != "" {
+		return title
+	}
+
+	if name := source.DiagramName(src.Content); name != "" {
+		return name
+	}
+
+	if input == stdinFilename {
+		return "diagram"
+	}
+
+	return strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+}
+
+// warnLinkLength writes a warning to stderr when the link is too long for some servers.
+func (b *builder) warnLinkLength(src source.Source, link string) {
+	if warning := render.LinkWarning(link); warning != "" {
+		d := diagnostic.Diagnostic{File: displayPath(src.Origin(1).Filepath), Message: "warning: " + warning}
+		b.cmd.PrintErrln(d.String())
+	}
+}
+
+func (b *builder) addDiagnostic(src source.Source, diagramErr *render.DiagramError) {
+	d := diagnostic.Diagnostic{Message: diagramErr.Message}
+
+	if len(src.Origins) > 0 {
+		origin := src.Origin(diagramErr.Line)
+		d.File = displayPath(origin.Filepath)
+
+		if diagramErr.Line > 0 {
+			d.Line = origin.Line
+		}
+	}
+
+	b.diagnostics = append(b.diagnostics, d)
+}
+
+// reportDiagnostics writes the collected diagnostics and the files which are out of date to stderr
+// and returns an error if there were any.
+func (b *builder) reportDiagnostics() error {
+	if len(b.diagnostics) == 0 && len(b.outOfDate) == 0 {
+		return nil
+	}
+
+	if len(b.diagnostics) > 0 {
+		if err := diagnostic.Write(b.cmd.ErrOrStderr(), b.opts.ErrorFormat, b.diagnostics); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range b.outOfDate {
+		b.cmd.PrintErrln(path + ": out of date")
+	}
+
+	// The diagnostics are the error report, cobra shouldn't print the error or the usage.
+	b.cmd.SilenceErrors = true
+	b.cmd.SilenceUsage = true
+
+	if len(b.outOfDate) == 0 {
+		return fmt.Errorf("failed to build %d diagram(s)", len(b.diagnostics))
+	}
+
+	return fmt.Errorf("found %d diagram error(s) and %d file(s) out of date", len(b.diagnostics), len(b.outOfDate))
+}
+
+// displayPath returns the path relative to the working directory when possible.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+
+	return path
+}
+
 func findAbsolutePaths(args []string) (_ []string, err error) {
 	filepaths := make([]string, 0, len(args))
 	uniqueFileMap := make(map[string]bool)
@@ -207,7 +671,3 @@ func compressAndEncode(data []byte) (_ []byte, err error) {
 
 	return gopuml.Encode(data), nil
 }
-
-func createLink(server, format string, data []byte) string {
-	return fmt.Sprintf("%s/%s/%s", server, format, string(data))
-}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lonnblad/gopuml/internal/fsutil"
+)
+
+const (
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+// buildReport is the JSON report of a build.
+type buildReport struct {
+	Diagrams []*reportEntry `json:"diagrams"`
+}
+
+// reportEntry is a diagram built in a format.
+type reportEntry struct {
+	Input  string `json:"input"`
+	Name   string `json:"name,omitempty"`
+	Format string `json:"format"`
+	// Output is the file written with the file style, Written is false when it was already up to date.
+	Output  string `json:"output,omitempty"`
+	Written bool   `json:"written,omitempty"`
+	Link    string `json:"link"`
+
+	// Size and SHA256 are the size and hash of the rendered diagram,
+	// they are empty when the diagram isn't rendered.
+	Size       int     `json:"size,omitempty"`
+	SHA256     string  `json:"sha256,omitempty"`
+	DurationMs float64 `json:"durationMs,omitempty"`
+	// Cache is hit when a diagram with the same content was already rendered in the build.
+	Cache string `json:"cache,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func (entry *reportEntry) setContent(content []byte, cache string, duration time.Duration) {
+	hash := sha256.Sum256(content)
+
+	entry.Size = len(content)
+	entry.SHA256 = hex.EncodeToString(hash[:])
+	entry.DurationMs = float64(duration) / float64(time.Millisecond)
+	entry.Cache = cache
+}
+
+// writeReport writes the report to the report file, and to stdout with the json style.
+func (b *builder) writeReport() error {
+	if b.opts.Report == "" && b.opts.Style != styleJSON {
+		return nil
+	}
+
+	report := buildReport{Diagrams: b.report}
+	if report.Diagrams == nil {
+		report.Diagrams = []*reportEntry{}
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't create the build report: %w", err)
+	}
+
+	content = append(content, '\n')
+
+	if b.opts.Style == styleJSON {
+		if _, err = b.cmd.OutOrStdout().Write(content); err != nil {
+			return fmt.Errorf("couldn't write the build report: %w", err)
+		}
+	}
+
+	if b.opts.Report == "" {
+		return nil
+	}
+
+	const readWriteMode = 0600
+	if _, err = fsutil.WriteFile(b.opts.Report, content, readWriteMode); err != nil {
+		return fmt.Errorf("couldn't write the build report: %w", err)
+	}
+
+	return nil
+}
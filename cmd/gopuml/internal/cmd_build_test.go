@@ -2,18 +2,26 @@ package internal_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
 	"image"
 	_ "image/png"
+	"math/rand"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lonnblad/gopuml"
 	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
 	"github.com/lonnblad/gopuml/example"
+	"github.com/lonnblad/gopuml/plantumltest"
 )
 
 const (
@@ -27,7 +35,15 @@ const (
 )
 
 func Test_RunBuildCommand(t *testing.T) {
-	testcases := createBuildCmdTestcases()
+	server := plantumltest.NewServer()
+	server.SetFixture(formatPNG, []byte(example.PNGFile()))
+	server.SetFixture(formatSVG, []byte(example.SVGFile()))
+	server.SetFixture(formatTXT, []byte(example.TXTFile()))
+
+	// The subtests are parallel, the server is closed when they are done.
+	t.Cleanup(server.Close)
+
+	testcases := createBuildCmdTestcases(server.URL)
 
 	for _, tc := range testcases {
 		t.Run(tc.name("stdin"), tc.testStdin)
@@ -36,13 +52,15 @@ func Test_RunBuildCommand(t *testing.T) {
 }
 
 type buildCmdTestcase struct {
+	server         string
 	format         string
 	style          string
 	expectedOutput string
 }
 
-func createBuildCmdTestcases() []buildCmdTestcase {
-	return []buildCmdTestcase{
+// createBuildCmdTestcases creates the testcases, the styles which render the diagrams use the server.
+func createBuildCmdTestcases(server string) []buildCmdTestcase {
+	testcases := []buildCmdTestcase{
 		{
 			format: formatPNG, style: styleFile,
 			expectedOutput: example.PNGFile(),
@@ -80,9 +98,21 @@ func createBuildCmdTestcases() []buildCmdTestcase {
 			expectedOutput: example.TXTFile(),
 		},
 	}
+
+	for i := range testcases {
+		if testcases[i].style != styleLink {
+			testcases[i].server = server
+		}
+	}
+
+	return testcases
 }
 
 func (tc buildCmdTestcase) args(args ...string) []string {
+	if tc.server != "" {
+		args = append([]string{"--server", tc.server}, args...)
+	}
+
 	return append([]string{"-f", tc.format, "--style", tc.style}, args...)
 }
 
@@ -134,7 +164,7 @@ func (tc buildCmdTestcase) executeAndValidate(t *testing.T, tempDir string, cmd
 	actualOutput := stdout.String()
 
 	if tc.style == styleLink {
-		assert.Equal(t, tc.expectedOutput, actualOutput)
+		equalLinks(t, tc.expectedOutput, actualOutput)
 		return
 	}
 
@@ -191,3 +221,582 @@ func equalImages(t *testing.T, expected, actual string) {
 		}
 	}
 }
+
+// equalLinks compares the server, the format and the decoded Plant UML of the links,
+// since the deflate output differs between versions of Go.
+func equalLinks(t *testing.T, expected, actual string) {
+	t.Helper()
+
+	expectedIdx := strings.LastIndex(expected, "/")
+	actualIdx := strings.LastIndex(actual, "/")
+
+	require.Equal(t, expected[:expectedIdx], actual[:actualIdx])
+	assert.Equal(t, strings.HasSuffix(expected, "\n"), strings.HasSuffix(actual, "\n"))
+	assert.Equal(t, decodeLink(t, expected[expectedIdx+1:]), decodeLink(t, actual[actualIdx+1:]))
+}
+
+func decodeLink(t *testing.T, encoded string) string {
+	t.Helper()
+
+	compressed, err := gopuml.Decode([]byte(strings.TrimSpace(encoded)))
+	require.Nil(t, err)
+
+	content, err := gopuml.Inflate(compressed)
+	require.Nil(t, err)
+
+	return string(content)
+}
+
+func Test_RunBuildCommandDiagnostics(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetDiagramError("Bob -> \n", 3, "Syntax Error?")
+
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+	includedFile := tempDir + "/" + "included.puml"
+
+	err := os.WriteFile(includedFile, []byte("@startuml\nAlice -> Bob : hi\nBob -> \n@enduml"), 0600)
+	require.Nil(t, err)
+
+	err = os.WriteFile(inputFile, []byte("@startuml Example\n!include included.puml\n@enduml"), 0600)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		errorFormat    string
+		expectedStderr string
+	}{
+		{
+			errorFormat:    "text",
+			expectedStderr: includedFile + ":3: Syntax Error?\n",
+		},
+		{
+			errorFormat:    "json",
+			expectedStderr: "[\n  {\n    \"file\": \"" + includedFile + "\",\n    \"line\": 3,\n    \"message\": \"Syntax Error?\"\n  }\n]\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs([]string{"--style", styleOut, "--server", server.URL, "--error-format", tc.errorFormat, inputFile})
+
+		var stdout, stderr bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+
+		err = cmd.Execute()
+		assert.NotNil(t, err)
+		assert.Empty(t, stdout.String())
+		assert.Equal(t, tc.expectedStderr, stderr.String())
+	}
+}
+
+func Test_RunBuildCommandWritesChangedFiles(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatTXT, []byte("a longer first version"))
+
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+	outputFile := tempDir + "/" + "example.txt"
+
+	err := os.WriteFile(inputFile, []byte(example.PUML()), 0600)
+	require.Nil(t, err)
+
+	build := func(args ...string) string {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs(append([]string{"-f", formatTXT, "--server", server.URL}, append(args, inputFile)...))
+
+		var stdout, stderr bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+
+		err := cmd.Execute()
+		require.Nil(t, err)
+		assert.Empty(t, stderr.String())
+
+		return stdout.String()
+	}
+
+	assert.Equal(t, "updated file: "+outputFile+"\n", build())
+
+	past := time.Now().Add(-time.Hour)
+	require.Nil(t, os.Chtimes(outputFile, past, past))
+
+	assert.Empty(t, build())
+
+	info, err := os.Stat(outputFile)
+	require.Nil(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	server.SetFixture(formatTXT, []byte("shorter"))
+
+	assert.Empty(t, build("--quiet"))
+
+	content, err := os.ReadFile(outputFile)
+	require.Nil(t, err)
+	assert.Equal(t, "shorter", string(content))
+}
+
+func Test_RunBuildCommandCheck(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatTXT, []byte("rendered"))
+
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+	outputFile := tempDir + "/" + "example.txt"
+
+	require.Nil(t, os.WriteFile(inputFile, []byte(example.PUML()), 0600))
+
+	check := func(args ...string) (string, error) {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs(append([]string{"-f", formatTXT, "--server", server.URL, "--check"}, append(args, inputFile)...))
+
+		var stderr bytes.Buffer
+
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&stderr)
+
+		err := cmd.Execute()
+
+		return stderr.String(), err
+	}
+
+	stderr, err := check()
+	assert.EqualError(t, err, "found 0 diagram error(s) and 1 file(s) out of date")
+	assert.Equal(t, outputFile+": out of date\n", stderr)
+	assert.NoFileExists(t, outputFile)
+
+	require.Nil(t, os.WriteFile(outputFile, []byte("rendered"), 0600))
+
+	stderr, err = check()
+	assert.Nil(t, err)
+	assert.Empty(t, stderr)
+
+	_, err = check("--style", styleLink)
+	assert.NotNil(t, err)
+}
+
+func Test_RunBuildCommandWithConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := tempDir + "/" + ".gopuml.yaml"
+	inputFile := tempDir + "/" + "example.puml"
+
+	err := os.WriteFile(inputFile, []byte(example.PUML()), 0600)
+	require.Nil(t, err)
+
+	err = os.WriteFile(configFile, []byte("server: https://config.example.com\nformat: png\nstyle: link\n"), 0600)
+	require.Nil(t, err)
+
+	build := func(args ...string) string {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs(append([]string{"--config", configFile}, append(args, inputFile)...))
+
+		var stdout bytes.Buffer
+
+		cmd.SetOut(&stdout)
+
+		require.Nil(t, cmd.Execute())
+
+		return stdout.String()
+	}
+
+	assert.True(t, strings.HasPrefix(build(), "https://config.example.com/png/"))
+
+	t.Setenv("GOPUML_FORMAT", "txt")
+	assert.True(t, strings.HasPrefix(build(), "https://config.example.com/txt/"))
+
+	t.Setenv("GOPUML_SERVER", "https://env.example.com")
+	assert.True(t, strings.HasPrefix(build("-f", "svg"), "https://env.example.com/svg/"))
+}
+
+func Test_RunBuildCommandWithTarget(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("svg"))
+	server.SetFixture(formatTXT, []byte("txt"))
+
+	tempDir := t.TempDir()
+	configFile := tempDir + "/" + ".gopuml.yaml"
+
+	require.Nil(t, os.MkdirAll(tempDir+"/docs/nested", 0700))
+	require.Nil(t, os.WriteFile(tempDir+"/docs/nested/example.puml", []byte(example.PUML()), 0600))
+
+	config := "server: " + server.URL + "\n" +
+		"targets:\n" +
+		"  docs:\n" +
+		"    inputs: [docs/**/*.puml]\n" +
+		"    formats: [svg, txt]\n" +
+		"    outDir: docs/img\n"
+
+	require.Nil(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--config", configFile, "--target", "docs", "--quiet"})
+
+	require.Nil(t, cmd.Execute())
+
+	content, err := os.ReadFile(tempDir + "/docs/img/nested/example.svg")
+	require.Nil(t, err)
+	assert.Equal(t, "svg", string(content))
+
+	content, err = os.ReadFile(tempDir + "/docs/img/nested/example.txt")
+	require.Nil(t, err)
+	assert.Equal(t, "txt", string(content))
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--config", configFile, "--target", "missing"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+
+	require.Nil(t, os.MkdirAll(tempDir+"/other", 0700))
+	require.Nil(t, os.WriteFile(tempDir+"/other/example.puml", []byte(example.PUML()), 0600))
+
+	config = "server: " + server.URL + "\n" +
+		"targets:\n" +
+		"  docs:\n" +
+		"    inputs: [docs/nested/*.puml, other/*.puml]\n" +
+		"    outDir: docs/img\n"
+
+	require.Nil(t, os.WriteFile(configFile, []byte(config), 0600))
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--config", configFile, "--target", "docs"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	err = cmd.Execute()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "/other/example.puml] would both be written to [")
+}
+
+func Test_RunBuildCommandWithClientOptions(t *testing.T) {
+	server := plantumltest.NewTLSServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("rendered"))
+
+	tempDir := t.TempDir()
+	caFile := tempDir + "/" + "ca.pem"
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.Nil(t, os.WriteFile(caFile, caPEM, 0600))
+
+	t.Setenv("GOPUML_TOKEN", "secret")
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs([]string{
+		"--server", server.URL, "--style", styleOut, "--ca-cert", caFile, "--header", "X-Team: docs",
+	})
+	cmd.SetIn(bytes.NewBufferString(example.PUML()))
+
+	var stdout bytes.Buffer
+
+	cmd.SetOut(&stdout)
+
+	require.Nil(t, cmd.Execute())
+	assert.Equal(t, "rendered", stdout.String())
+
+	received := server.Requests()[0]
+	assert.Equal(t, "docs", received.Header.Get("X-Team"))
+	assert.Equal(t, "Bearer secret", received.Header.Get("Authorization"))
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--server", server.URL, "--style", styleOut, "--header", "invalid"})
+	cmd.SetIn(bytes.NewBufferString(example.PUML()))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+}
+
+func Test_RunBuildCommandWithLargeDiagram(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("rendered"))
+
+	random := rand.New(rand.NewSource(1)) // nolint: gosec
+	note := make([]byte, 4000)
+
+	for idx := range note {
+		note[idx] = byte('a' + random.Intn(26))
+	}
+
+	diagram := "@startuml\nnote \"" + string(note) + "\" as N\n@enduml\n"
+
+	execute := func(args ...string) (string, string) {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs(append([]string{"--server", server.URL}, args...))
+		cmd.SetIn(bytes.NewBufferString(diagram))
+
+		var stdout, stderr bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+
+		require.Nil(t, cmd.Execute())
+
+		return stdout.String(), stderr.String()
+	}
+
+	_, stderr := execute("--style", styleLink)
+	assert.Contains(t, stderr, "<stdin>: warning: the link is ")
+	assert.Contains(t, stderr, "longer than 2048 characters")
+
+	stdout, _ := execute("--style", styleOut)
+	assert.Equal(t, "rendered", stdout)
+
+	_, _ = execute("--style", styleOut, "--max-link-length", "100")
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, http.MethodGet, requests[0].Method)
+	assert.Equal(t, http.MethodPost, requests[1].Method)
+	assert.Equal(t, diagram, requests[1].Source)
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--server", server.URL, "--style", styleOut, "--method", "put"})
+	cmd.SetIn(bytes.NewBufferString(diagram))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+}
+
+func Test_RunBuildCommandWithRetries(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("rendered"))
+
+	tooManyRequests := plantumltest.Failure{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"0"}}}
+
+	execute := func(args ...string) (string, error) {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs(append([]string{"--server", server.URL, "--style", styleOut, "--rate", "100/s"}, args...))
+		cmd.SetIn(bytes.NewBufferString(example.PUML()))
+
+		var stdout bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&bytes.Buffer{})
+
+		err := cmd.Execute()
+
+		return stdout.String(), err
+	}
+
+	server.FailNext(tooManyRequests)
+
+	stdout, err := execute()
+	require.Nil(t, err)
+	assert.Equal(t, "rendered", stdout)
+	assert.Len(t, server.Requests(), 2)
+
+	server.FailNext(tooManyRequests)
+
+	_, err = execute("--retries", "0")
+	assert.NotNil(t, err)
+	assert.Len(t, server.Requests(), 3)
+
+	_, err = execute("--rate", "fast")
+	assert.NotNil(t, err)
+}
+
+func Test_RunBuildCommandCancelled(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetLatency(time.Minute)
+
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+
+	require.Nil(t, os.WriteFile(inputFile, []byte(example.PUML()), 0600))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--server", server.URL, inputFile})
+	cmd.SetOut(&bytes.Buffer{})
+
+	var stderr bytes.Buffer
+
+	cmd.SetErr(&stderr)
+
+	err := cmd.ExecuteContext(ctx)
+	assert.EqualError(t, err, "interrupted")
+	assert.NotContains(t, stderr.String(), "Usage:")
+
+	entries, err := os.ReadDir(tempDir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_RunBuildCommandWithReport(t *testing.T) {
+	const invalidDiagram = "@startuml Invalid\ninvalid\n@enduml\n"
+
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("<svg/>"))
+	server.SetDiagramError("\ninvalid\n", 2, "Syntax Error?")
+
+	tempDir := t.TempDir()
+	reportFile := tempDir + "/" + "report.json"
+	files := []string{tempDir + "/" + "a.puml", tempDir + "/" + "b.puml", tempDir + "/" + "invalid.puml"}
+
+	require.Nil(t, os.WriteFile(files[0], []byte(example.PUML()), 0600))
+	require.Nil(t, os.WriteFile(files[1], []byte(example.PUML()), 0600))
+	require.Nil(t, os.WriteFile(files[2], []byte(invalidDiagram), 0600))
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs(append([]string{"--server", server.URL, "--quiet", "--report", reportFile}, files...))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+	assert.Len(t, server.Requests(), 2)
+
+	content, err := os.ReadFile(reportFile)
+	require.Nil(t, err)
+
+	type entry struct {
+		Input   string `json:"input"`
+		Name    string `json:"name"`
+		Format  string `json:"format"`
+		Output  string `json:"output"`
+		Written bool   `json:"written"`
+		Link    string `json:"link"`
+		Size    int    `json:"size"`
+		SHA256  string `json:"sha256"`
+		Cache   string `json:"cache"`
+		Error   string `json:"error"`
+	}
+
+	var report struct {
+		Diagrams []entry `json:"diagrams"`
+	}
+
+	require.Nil(t, json.Unmarshal(content, &report))
+	require.Len(t, report.Diagrams, 3)
+
+	first, second, invalid := report.Diagrams[0], report.Diagrams[1], report.Diagrams[2]
+
+	assert.Equal(t, files[0], first.Input)
+	assert.Equal(t, formatSVG, first.Format)
+	assert.Equal(t, tempDir+"/"+"a.svg", first.Output)
+	assert.True(t, first.Written)
+	assert.True(t, strings.HasPrefix(first.Link, server.URL+"/svg/"))
+	assert.Equal(t, len("<svg/>"), first.Size)
+	assert.Len(t, first.SHA256, 64)
+	assert.Equal(t, "miss", first.Cache)
+
+	assert.Equal(t, "hit", second.Cache)
+	assert.Equal(t, first.SHA256, second.SHA256)
+
+	assert.Equal(t, "Invalid", invalid.Name)
+	assert.Equal(t, "line 2: Syntax Error?", invalid.Error)
+	assert.Empty(t, invalid.Output)
+
+	// The report is written when the build fails as well.
+	server.FailNext(plantumltest.Failure{StatusCode: http.StatusNotFound})
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs(append([]string{"--server", server.URL, "--quiet", "--report", reportFile}, files...))
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+
+	content, err = os.ReadFile(reportFile)
+	require.Nil(t, err)
+
+	report.Diagrams = nil
+	require.Nil(t, json.Unmarshal(content, &report))
+	require.Len(t, report.Diagrams, 1)
+	assert.Equal(t, files[0], report.Diagrams[0].Input)
+	assert.Contains(t, report.Diagrams[0].Error, "404 Not Found")
+	assert.Empty(t, report.Diagrams[0].Output)
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--server", server.URL, "--style", "json", files[0]})
+
+	var stdout bytes.Buffer
+
+	cmd.SetOut(&stdout)
+
+	require.Nil(t, cmd.Execute())
+
+	report.Diagrams = nil
+	require.Nil(t, json.Unmarshal(stdout.Bytes(), &report))
+	require.Len(t, report.Diagrams, 1)
+	assert.Equal(t, first.SHA256, report.Diagrams[0].SHA256)
+	assert.Empty(t, report.Diagrams[0].Output)
+}
+
+func Test_RunBuildCommandWithReferenceStyles(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture(formatSVG, []byte("<svg/>"))
+
+	const diagram = "@startuml Flow\ntitle Request & Flow\nBob -> Alice : hello\n@enduml\n"
+
+	compressed, err := gopuml.Deflate([]byte(diagram))
+	require.Nil(t, err)
+
+	link := server.URL + "/svg/" + string(gopuml.Encode(compressed))
+
+	testcases := []struct {
+		style    string
+		expected string
+	}{
+		{style: "markdown", expected: "![Request & Flow](" + link + ")\n"},
+		{style: "html", expected: `<img src="` + link + `" alt="Request &amp; Flow">` + "\n"},
+		{style: "asciidoc", expected: "image::" + link + `["Request & Flow"]` + "\n"},
+		{style: "datauri", expected: "data:image/svg+xml;base64,PHN2Zy8+\n"},
+	}
+
+	for _, tc := range testcases {
+		cmd := internal.CreateBuildCmd()
+		cmd.SetArgs([]string{"--server", server.URL, "--style", tc.style})
+		cmd.SetIn(bytes.NewBufferString(diagram))
+
+		var stdout bytes.Buffer
+
+		cmd.SetOut(&stdout)
+
+		require.Nil(t, cmd.Execute(), tc.style)
+		assert.Equal(t, tc.expected, stdout.String(), tc.style)
+	}
+
+	cmd := internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--server", server.URL, "--style", "markdown"})
+	cmd.SetIn(bytes.NewBufferString("@startuml\nBob -> Alice : hello\n@enduml\n"))
+
+	var stdout bytes.Buffer
+
+	cmd.SetOut(&stdout)
+
+	require.Nil(t, cmd.Execute())
+	assert.True(t, strings.HasPrefix(stdout.String(), "![diagram]("), stdout.String())
+
+	cmd = internal.CreateBuildCmd()
+	cmd.SetArgs([]string{"--style", "unknown"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, cmd.Execute())
+}
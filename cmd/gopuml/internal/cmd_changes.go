@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/changes"
+)
+
+const flagChangedSince = "changed-since"
+
+const flagUsageChangedSince = `only use the files which have changed since the git ref, or which include a changed file,
+all Plant UML files in the working directory are used when no files are given,
+all files are used outside of a git repository
+ `
+
+const flagStaged = "staged"
+
+const flagUsageStaged = `read the files as they are staged in git, which is what will be committed,
+instead of the files in the working tree
+ `
+
+// readFunc returns the function to read files with, which reads the content staged in git with staged.
+func readFunc(staged bool) func(path string) ([]byte, error) {
+	if staged {
+		return changes.ReadStaged
+	}
+
+	return os.ReadFile
+}
+
+var plantUMLExtensions = map[string]bool{".puml": true, ".plantuml": true, ".pu": true}
+
+// changedFiles returns the files affected by the changes since the ref,
+// when no files are given, the Plant UML files in the working directory are used.
+// Outside of a git repository, a warning is written and all files are returned.
+func changedFiles(cmd *cobra.Command, ref string, files []string) ([]string, error) {
+	files, err := findAbsolutePaths(files)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		if files, err = findDocuments([]string{"."}, plantUMLExtensions); err != nil {
+			return nil, err
+		}
+	}
+
+	changed, err := changes.Since(".", ref)
+	if errors.Is(err, changes.ErrNotRepository) {
+		cmd.PrintErrf("warning: %s, --%s is ignored\n", err, flagChangedSince)
+		return files, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes.Affected(files, changed)
+}
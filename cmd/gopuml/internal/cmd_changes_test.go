@@ -0,0 +1,104 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+)
+
+func Test_RunBuildAndLintCommandChangedSince(t *testing.T) {
+	dir := newGitRepository(t)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "common.iuml"), []byte("skinparam monochrome true\n"), 0600))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "a.puml"), []byte("@startuml\n!include common.iuml\n@enduml\n"), 0600))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "b.puml"), []byte("@startuml\nBob -> Alice\n@enduml\n"), 0600))
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "common.iuml"), []byte("skinparam monochrome false\n"), 0600))
+
+	chdir(t, dir)
+
+	build := internal.CreateBuildCmd()
+	build.SetArgs([]string{"--style", "link", "--server", "https://plantuml.example.com", "--changed-since", "HEAD"})
+
+	var stdout bytes.Buffer
+
+	build.SetOut(&stdout)
+
+	require.Nil(t, build.Execute())
+	assert.Equal(t, 1, bytes.Count(stdout.Bytes(), []byte("\n")), stdout.String())
+
+	lint := internal.CreateLintCmd()
+	lint.SetArgs([]string{"--changed-since", "HEAD", "a.puml", "b.puml"})
+
+	stdout.Reset()
+	lint.SetOut(&stdout)
+	lint.SetErr(&bytes.Buffer{})
+
+	assert.NotNil(t, lint.Execute())
+	assert.Contains(t, stdout.String(), "a.puml:1: diagram has no name after @startuml")
+	assert.NotContains(t, stdout.String(), "b.puml")
+
+	chdir(t, t.TempDir())
+	require.Nil(t, os.WriteFile("c.puml", []byte("@startuml C\n@enduml\n"), 0600))
+
+	lint = internal.CreateLintCmd()
+	lint.SetArgs([]string{"--changed-since", "HEAD"})
+
+	var stderr bytes.Buffer
+
+	stdout.Reset()
+	lint.SetOut(&stdout)
+	lint.SetErr(&stderr)
+
+	require.Nil(t, lint.Execute())
+	assert.Equal(t, "warning: not inside a git repository, --changed-since is ignored\n", stderr.String())
+}
+
+// newGitRepository creates a git repository in a temporary directory,
+// isolated from the git configuration of the user.
+func newGitRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "config", "user.name", "gopuml")
+	runGit(t, dir, "config", "user.email", "gopuml@example.com")
+
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(output))
+
+	return string(output)
+}
+
+// chdir changes the working directory for the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) }) // nolint: errcheck
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/lonnblad/gopuml/internal/render"
+)
+
+const (
+	flagHeader     = "header"
+	flagToken      = "token"
+	flagTokenFile  = "token-file"
+	flagBasicAuth  = "basic-auth"
+	flagCACert     = "ca-cert"
+	flagClientCert = "client-cert"
+	flagClientKey  = "client-key"
+	flagProxy      = "proxy"
+	flagTimeout    = "timeout"
+
+	flagMethod        = "method"
+	flagMaxLinkLength = "max-link-length"
+
+	flagRetries          = "retries"
+	flagRate             = "rate"
+	flagBreakerThreshold = "breaker-threshold"
+
+	defaultRetries          = 3
+	defaultBreakerThreshold = 5
+)
+
+// clientFlags are the flags added by addClientFlags.
+var clientFlags = []string{
+	flagHeader, flagToken, flagTokenFile, flagBasicAuth, flagCACert, flagClientCert, flagClientKey, flagProxy, flagTimeout,
+	flagMethod, flagMaxLinkLength, flagRetries, flagRate, flagBreakerThreshold,
+}
+
+// clientOptions are the options for the requests to the Plant UML server.
+type clientOptions struct {
+	Headers    []string
+	Token      string
+	TokenFile  string
+	BasicAuth  string
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	Proxy      string
+	Timeout    time.Duration
+
+	Method        string
+	MaxLinkLength int
+
+	Retries          int
+	Rate             string
+	BreakerThreshold int
+}
+
+const flagUsageHeader = `a header to add to the requests to the Plant UML server, formatted like "Name: Value",
+can be repeated
+ `
+
+const flagUsageToken = `a token to send as a bearer token to the Plant UML server,
+preferably set with the environment variable GOPUML_TOKEN
+ `
+
+const flagUsageTokenFile = `a file with a token to send as a bearer token to the Plant UML server
+ `
+
+const flagUsageBasicAuth = `the username and password to send to the Plant UML server, formatted like "username:password",
+preferably set with the environment variable GOPUML_BASIC_AUTH
+ `
+
+const flagUsageCACert = `a PEM file with CA certificates to trust in addition to the system certificates
+ `
+
+const flagUsageClientCert = `a PEM file with a client certificate to use for the Plant UML server
+ `
+
+const flagUsageClientKey = `a PEM file with the key of the client certificate
+ `
+
+const flagUsageProxy = `the URL of a proxy to use for the Plant UML server,
+defaults to the proxy in the HTTP_PROXY and HTTPS_PROXY environment variables
+ `
+
+const flagUsageTimeout = `the time limit of a request to the Plant UML server, 0 means no limit
+ `
+
+const flagUsageMethod = `the method to use to render the diagrams
+
+supported methods are:
+  ` + render.MethodAuto + `  will use post for links longer than the max link length, otherwise get
+  ` + render.MethodGet + `   will request the link to the diagram
+  ` + render.MethodPost + `  will post the Plant UML to "<server_url>/<format>"
+ `
+
+const flagUsageMaxLinkLength = `the longest link to render with a get request when using the auto method
+ `
+
+const flagUsageRetries = `the number of times to retry a request when the Plant UML server is temporarily unavailable,
+with an exponential backoff, or as long as the server asks in a Retry-After header
+ `
+
+const flagUsageRate = `the max rate of requests to the Plant UML server, like 10/s, 100/m or 1000/h,
+defaults to no limit
+ `
+
+const flagUsageBreakerThreshold = `the number of failed requests in a row after which requests to the Plant UML server
+fail fast for 30s, 0 disables it
+ `
+
+// addClientFlags adds the flags which configure the requests to the Plant UML server.
+func addClientFlags(flags *pflag.FlagSet, opts *clientOptions) {
+	flags.StringArrayVar(&opts.Headers, flagHeader, opts.Headers, flagUsageHeader)
+	flags.StringVar(&opts.Token, flagToken, opts.Token, flagUsageToken)
+	flags.StringVar(&opts.TokenFile, flagTokenFile, opts.TokenFile, flagUsageTokenFile)
+	flags.StringVar(&opts.BasicAuth, flagBasicAuth, opts.BasicAuth, flagUsageBasicAuth)
+	flags.StringVar(&opts.CACert, flagCACert, opts.CACert, flagUsageCACert)
+	flags.StringVar(&opts.ClientCert, flagClientCert, opts.ClientCert, flagUsageClientCert)
+	flags.StringVar(&opts.ClientKey, flagClientKey, opts.ClientKey, flagUsageClientKey)
+	flags.StringVar(&opts.Proxy, flagProxy, opts.Proxy, flagUsageProxy)
+	flags.DurationVar(&opts.Timeout, flagTimeout, opts.Timeout, flagUsageTimeout)
+	flags.StringVar(&opts.Method, flagMethod, render.MethodAuto, flagUsageMethod)
+	flags.IntVar(&opts.MaxLinkLength, flagMaxLinkLength, render.DefaultMaxLinkLength, flagUsageMaxLinkLength)
+	flags.IntVar(&opts.Retries, flagRetries, defaultRetries, flagUsageRetries)
+	flags.StringVar(&opts.Rate, flagRate, opts.Rate, flagUsageRate)
+	flags.IntVar(&opts.BreakerThreshold, flagBreakerThreshold, defaultBreakerThreshold, flagUsageBreakerThreshold)
+}
+
+// newRenderer creates a Renderer for the server, which uses the client options for all requests.
+func newRenderer(server string, opts clientOptions) (*render.Renderer, error) {
+	renderOpts := render.ClientOptions{
+		Header:         http.Header{},
+		Token:          opts.Token,
+		CACertFile:     opts.CACert,
+		ClientCertFile: opts.ClientCert,
+		ClientKeyFile:  opts.ClientKey,
+		Proxy:          opts.Proxy,
+		Timeout:        opts.Timeout,
+		Method:         opts.Method,
+		MaxLinkLength:  opts.MaxLinkLength,
+
+		Retries:          opts.Retries,
+		BreakerThreshold: opts.BreakerThreshold,
+	}
+
+	if opts.Rate != "" {
+		var err error
+		if renderOpts.Rate, err = render.ParseRate(opts.Rate); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, header := range opts.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header: [%s], expected \"Name: Value\"", header)
+		}
+
+		renderOpts.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	if opts.Token != "" && opts.TokenFile != "" {
+		return nil, fmt.Errorf("can't use both --%s and --%s", flagToken, flagTokenFile)
+	}
+
+	if opts.TokenFile != "" {
+		token, err := os.ReadFile(opts.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the token file: %w", err)
+		}
+
+		renderOpts.Token = strings.TrimSpace(string(token))
+	}
+
+	if opts.BasicAuth != "" {
+		var ok bool
+		if renderOpts.Username, renderOpts.Password, ok = strings.Cut(opts.BasicAuth, ":"); !ok {
+			return nil, fmt.Errorf("invalid basic auth, expected \"username:password\"")
+		}
+	}
+
+	return render.NewWithOptions(server, renderOpts)
+}
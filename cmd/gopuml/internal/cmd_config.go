@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/config"
+)
+
+const flagConfig = "config"
+
+const flagUsageConfig = `the configuration file to use, defaults to the closest ` + config.Filename + `
+in the working directory or its parents
+ `
+
+// addConfigFlag adds the flag to set the configuration file used by loadConfig.
+func addConfigFlag(cmd *cobra.Command) {
+	cmd.Flags().String(flagConfig, "", flagUsageConfig)
+}
+
+// loadConfig loads the configuration file set by the config flag or its environment variable,
+// or the closest configuration file. An empty configuration is returned when there is no file.
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	path, _ := cmd.Flags().GetString(flagConfig) // nolint: errcheck
+
+	if path == "" {
+		path = os.Getenv(config.EnvName(flagConfig))
+	}
+
+	if path == "" {
+		var err error
+		if path, err = config.Find("."); err != nil || path == "" {
+			return config.Config{}, err
+		}
+	}
+
+	return config.Load(path)
+}
+
+// applyConfig sets the flags which aren't set on the command line from their environment variable
+// or the configuration file, for commands which don't use the rest of the configuration.
+// Only the given flags are set.
+func applyConfig(cmd *cobra.Command, flags ...string) error {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	return applyDefaults(cmd, cfg, flags...)
+}
+
+// applyDefaults sets the given flags which aren't set on the command line,
+// from their environment variable or else from the configuration.
+// Every command lists its own flags, since flags of different commands can share a name but not its meaning,
+// like the format of the diagrams and the format of the lint report.
+func applyDefaults(cmd *cobra.Command, cfg config.Config, flags ...string) error {
+	defaults := cfg.Defaults()
+
+	for _, name := range flags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+
+		value, ok := os.LookupEnv(config.EnvName(flag.Name))
+		source := config.EnvName(flag.Name)
+
+		if !ok {
+			value, ok = defaults[flag.Name]
+			source = cfg.Path
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid value for %s from %s: %w", flag.Name, source, err)
+		}
+	}
+
+	return nil
+}
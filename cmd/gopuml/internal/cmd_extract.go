@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/docs"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+const (
+	defaultServerPattern = "plantuml"
+
+	flagServerPattern = "server-pattern"
+	flagRewrite       = "rewrite"
+)
+
+type extractOptions struct {
+	ServerPattern string
+	OutDir        string
+	Rewrite       bool
+}
+
+const flagUsageServerPattern = `a regular expression the server of a link has to match
+for the link to be extracted
+ `
+
+const flagUsageExtractOutDir = `the directory to write the Plant UML files to, relative to the document
+ `
+
+const flagUsageRewrite = `rewrite the documents to add a sync marker before every extracted link,
+which references the extracted file, see the sync-links command
+ `
+
+// extractConfigFlags are the flags of extract which are set from the environment or the configuration.
+var extractConfigFlags = []string{flagServerPattern, flagOutDir, flagRewrite}
+
+// CreateExtractCmd creates the extract subcommand.
+// The command finds links to diagrams rendered by a Plant UML server in documents,
+// decodes the diagrams and writes them to Plant UML files.
+func CreateExtractCmd() cobra.Command {
+	opts := extractOptions{
+		ServerPattern: defaultServerPattern,
+	}
+
+	extractCmd := cobra.Command{
+		Use:   "extract [documents or directories]",
+		Short: "Extracts diagrams linked in documents into Plant UML files",
+		Long: `Extracts the diagrams linked in Markdown, AsciiDoc and HTML documents into Plant UML files.
+
+Every link to a Plant UML server, like:
+  https://www.plantuml.com/plantuml/svg/<encoded diagram>
+is decoded and written to a .puml file named after the title of the diagram, its name,
+or after the document when the diagram has neither. Existing files are never overwritten, a suffix is added
+to the name instead.
+
+With --rewrite, a sync marker referencing the extracted file is added before the link,
+which lets the sync-links command keep the link up to date.`,
+		Example: `  gopuml extract README.md
+  gopuml extract --out-dir diagrams --rewrite docs
+  gopuml extract --server-pattern 'plantuml\.example\.com' docs`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: extractCmdRunFunc(&opts),
+	}
+
+	extractCmd.Flags().StringVar(&opts.ServerPattern, flagServerPattern, opts.ServerPattern, flagUsageServerPattern)
+	extractCmd.Flags().StringVar(&opts.OutDir, flagOutDir, opts.OutDir, flagUsageExtractOutDir)
+	extractCmd.Flags().BoolVar(&opts.Rewrite, flagRewrite, opts.Rewrite, flagUsageRewrite)
+	addConfigFlag(&extractCmd)
+
+	return extractCmd
+}
+
+func extractCmdRunFunc(opts *extractOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, extractConfigFlags...); err != nil {
+			return err
+		}
+
+		serverRegexp, err := regexp.Compile(opts.ServerPattern)
+		if err != nil {
+			return fmt.Errorf("invalid server pattern: %w", err)
+		}
+
+		filepaths, err := findDocuments(args, linkDocumentExtensions)
+		if err != nil {
+			return err
+		}
+
+		e := extractor{opts: *opts, cmd: cmd, serverRegexp: serverRegexp}
+
+		for _, path := range filepaths {
+			if err = e.extractFile(path); err != nil {
+				return err
+			}
+		}
+
+		return e.report()
+	}
+}
+
+// extractor extracts the linked diagrams in documents and collects the diagnostics.
+type extractor struct {
+	opts         extractOptions
+	cmd          *cobra.Command
+	serverRegexp *regexp.Regexp
+	diagnostics  []diagnostic.Diagnostic
+}
+
+func (e *extractor) extractFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	outDir := e.opts.OutDir
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(filepath.Dir(path), outDir)
+	}
+
+	docName := slug(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	lines := docs.SplitLines(string(content))
+	updated := make([]string, 0, len(lines))
+	noOfDiagrams := 0
+
+	for idx, line := range lines {
+		var srcPaths []string
+
+		for _, link := range docs.FindLinks(line) {
+			if !e.serverRegexp.MatchString(link.Server) {
+				continue
+			}
+
+			noOfDiagrams++
+
+			srcPath, err := e.extractLink(outDir, fmt.Sprintf("%s-%d", docName, noOfDiagrams), link)
+			if err != nil {
+				e.addDiagnostic(path, idx+1, err.Error())
+				continue
+			}
+
+			srcPaths = append(srcPaths, srcPath)
+		}
+
+		if e.opts.Rewrite && len(srcPaths) > 0 && !docs.IsMarked(lines, idx) {
+			if len(srcPaths) > 1 {
+				e.addDiagnostic(path, idx+1, "can't add a sync marker to a line with several links")
+			} else {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				marker := docs.FormatMarker(filepath.Ext(path), relativeLink(filepath.Dir(path), srcPaths[0]))
+				updated = append(updated, indent+marker)
+			}
+		}
+
+		updated = append(updated, line)
+	}
+
+	if !e.opts.Rewrite {
+		return nil
+	}
+
+	newContent := []byte(strings.Join(updated, lineEnding(content)))
+	if bytes.Equal(content, newContent) {
+		return nil
+	}
+
+	const readWriteMode = 0600
+	if _, err = fsutil.WriteFile(path, newContent, readWriteMode); err != nil {
+		return fmt.Errorf("couldn't write file: %w", err)
+	}
+
+	fmt.Fprintln(e.cmd.OutOrStdout(), "updated file:", displayPath(path))
+
+	return nil
+}
+
+// extractLink decodes the diagram in the link and writes it to a file in the directory,
+// named after the title of the diagram, its name or the fallback name. It returns the path to the file.
+func (e *extractor) extractLink(dir, fallbackName string, link docs.Link) (string, error) {
+	content, err := docs.DecodeLink(link)
+	if err != nil {
+		return "", fmt.Errorf("couldn't decode the link: %w", err)
+	}
+
+	name := slug(source.DiagramTitle(content))
+	if name == "" {
+		name = slug(source.DiagramName(content))
+	}
+
+	if name == "" {
+		name = fallbackName
+	}
+
+	const readWriteExecuteMode = 0700
+	if err = os.MkdirAll(dir, readWriteExecuteMode); err != nil {
+		return "", fmt.Errorf("couldn't create directory: %w", err)
+	}
+
+	for suffix := 1; ; suffix++ {
+		path := filepath.Join(dir, name+".puml")
+		if suffix > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d.puml", name, suffix))
+		}
+
+		existing, err := os.ReadFile(path)
+
+		switch {
+		case err == nil && bytes.Equal(existing, content):
+			return path, nil
+		case err == nil:
+			continue
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", err
+		}
+
+		const readWriteMode = 0600
+		if err = os.WriteFile(path, content, readWriteMode); err != nil {
+			return "", fmt.Errorf("couldn't write file: %w", err)
+		}
+
+		fmt.Fprintln(e.cmd.OutOrStdout(), "extracted file:", displayPath(path))
+
+		return path, nil
+	}
+}
+
+func (e *extractor) addDiagnostic(path string, line int, message string) {
+	e.diagnostics = append(e.diagnostics, diagnostic.Diagnostic{File: displayPath(path), Line: line, Message: message})
+}
+
+func (e *extractor) report() error {
+	if len(e.diagnostics) == 0 {
+		return nil
+	}
+
+	if err := diagnostic.Write(e.cmd.ErrOrStderr(), diagnostic.FormatText, e.diagnostics); err != nil {
+		return err
+	}
+
+	e.cmd.SilenceErrors = true
+	e.cmd.SilenceUsage = true
+
+	return fmt.Errorf("found %d problem(s)", len(e.diagnostics))
+}
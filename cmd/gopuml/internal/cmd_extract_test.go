@@ -0,0 +1,131 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+)
+
+func Test_RunExtractCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	docFile := tempDir + "/" + "README.md"
+
+	named := "@startuml Auth Flow\nBob -> Alice : hello\n@enduml\n"
+	unnamed := "@startuml\nAlice -> Bob : hi\n@enduml\n"
+	titled := "@startuml\ntitle Token Refresh\nAlice -> Bob : refresh\n@enduml\n"
+
+	doc := "# Example\n" +
+		"![auth](https://www.plantuml.com/plantuml/svg/" + encode(t, named) + ")\n" +
+		"  ![other](https://www.plantuml.com/plantuml/png/" + encode(t, unnamed) + ")\n" +
+		"![ignored](https://example.com/svg/" + encode(t, unnamed) + ")\n" +
+		"![refresh](https://www.plantuml.com/plantuml/svg/" + encode(t, titled) + ")\n"
+
+	err := os.WriteFile(docFile, []byte(doc), 0600)
+	require.Nil(t, err)
+
+	stdout, stderr, err := executeExtractCmd("--out-dir", "diagrams", tempDir)
+	require.Nil(t, err)
+	assert.Empty(t, stderr)
+	assert.Equal(t,
+		"extracted file: "+tempDir+"/diagrams/auth-flow.puml\n"+
+			"extracted file: "+tempDir+"/diagrams/readme-2.puml\n"+
+			"extracted file: "+tempDir+"/diagrams/token-refresh.puml\n",
+		stdout,
+	)
+
+	actual, err := os.ReadFile(tempDir + "/diagrams/auth-flow.puml")
+	require.Nil(t, err)
+	assert.Equal(t, named, string(actual))
+
+	actual, err = os.ReadFile(tempDir + "/diagrams/readme-2.puml")
+	require.Nil(t, err)
+	assert.Equal(t, unnamed, string(actual))
+
+	actual, err = os.ReadFile(tempDir + "/diagrams/token-refresh.puml")
+	require.Nil(t, err)
+	assert.Equal(t, titled, string(actual))
+
+	actualDoc, err := os.ReadFile(docFile)
+	require.Nil(t, err)
+	assert.Equal(t, doc, string(actualDoc))
+
+	stdout, _, err = executeExtractCmd("--out-dir", "diagrams", "--rewrite", docFile)
+	require.Nil(t, err)
+	assert.Equal(t, "updated file: "+docFile+"\n", stdout)
+
+	actualDoc, err = os.ReadFile(docFile)
+	require.Nil(t, err)
+
+	expectedDoc := "# Example\n" +
+		"<!-- gopuml:src=diagrams/auth-flow.puml -->\n" +
+		"![auth](https://www.plantuml.com/plantuml/svg/" + encode(t, named) + ")\n" +
+		"  <!-- gopuml:src=diagrams/readme-2.puml -->\n" +
+		"  ![other](https://www.plantuml.com/plantuml/png/" + encode(t, unnamed) + ")\n" +
+		"![ignored](https://example.com/svg/" + encode(t, unnamed) + ")\n" +
+		"<!-- gopuml:src=diagrams/token-refresh.puml -->\n" +
+		"![refresh](https://www.plantuml.com/plantuml/svg/" + encode(t, titled) + ")\n"
+	assert.Equal(t, expectedDoc, string(actualDoc))
+
+	stdout, _, err = executeExtractCmd("--out-dir", "diagrams", "--rewrite", docFile)
+	require.Nil(t, err)
+	assert.Empty(t, stdout)
+
+	err = os.WriteFile(docFile, []byte("![x](https://www.plantuml.com/plantuml/svg/abc)\n"), 0600)
+	require.Nil(t, err)
+
+	_, stderr, err = executeExtractCmd(docFile)
+	assert.NotNil(t, err)
+	assert.Contains(t, stderr, docFile+":1: couldn't decode the link")
+}
+
+func Test_RunExtractCommandThenSyncLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	docFile := tempDir + "/" + "README.md"
+
+	// The decoded diagram has no trailing newline, the extracted file must still match the link.
+	diagram := "@startuml\nBob -> Alice : hello\n@enduml"
+
+	doc := "![auth](https://www.plantuml.com/plantuml/svg/" + encode(t, diagram) + ")\n"
+	require.Nil(t, os.WriteFile(docFile, []byte(doc), 0600))
+
+	_, _, err := executeExtractCmd("--rewrite", docFile)
+	require.Nil(t, err)
+
+	actual, err := os.ReadFile(tempDir + "/readme-1.puml")
+	require.Nil(t, err)
+	assert.Equal(t, diagram, string(actual))
+
+	stdout, stderr, err := executeSyncLinksCmd("--check", docFile)
+	require.Nil(t, err, stderr)
+	assert.Empty(t, stdout)
+	assert.Empty(t, stderr)
+}
+
+func encode(t *testing.T, content string) string {
+	t.Helper()
+
+	compressed, err := gopuml.Deflate([]byte(content))
+	require.Nil(t, err)
+
+	return string(gopuml.Encode(compressed))
+}
+
+func executeExtractCmd(args ...string) (string, string, error) {
+	cmd := internal.CreateExtractCmd()
+	cmd.SetArgs(args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
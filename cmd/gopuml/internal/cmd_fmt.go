@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/formatter"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+)
+
+const (
+	flagWrite, flagShortWrite = "write", "w"
+	flagList, flagShortList   = "list", "l"
+	flagDiff, flagShortDiff   = "diff", "d"
+)
+
+type fmtOptions struct {
+	Write bool
+	List  bool
+	Diff  bool
+}
+
+const flagUsageWrite = `write the result to the source file instead of stdout
+ `
+
+const flagUsageList = `list the files whose formatting differs from gopuml's
+ `
+
+const flagUsageDiff = `display diffs instead of rewriting files
+ `
+
+// fmtConfigFlags are the flags of fmt which are set from the environment or the configuration.
+var fmtConfigFlags = []string{flagWrite, flagList, flagDiff}
+
+// CreateFmtCmd creates the fmt subcommand.
+// Like gofmt, the formatted content is written to stdout unless any of the flags are used.
+func CreateFmtCmd() cobra.Command {
+	opts := fmtOptions{}
+
+	fmtCmd := cobra.Command{
+		Use:   "fmt [plant UML files]",
+		Short: "Formats Plant UML files",
+		Long: `Formats Plant UML files.
+
+Lines are indented by their nesting depth, the spacing around arrows and colons
+in messages is normalized and directives and keywords are lower cased.
+Comments and unknown syntax are left untouched.`,
+		Example: `  gopuml fmt example.puml
+  gopuml fmt -l -w diagrams/*.puml`,
+		RunE: fmtCmdRunFunc(&opts),
+	}
+
+	fmtCmd.Flags().BoolVarP(&opts.Write, flagWrite, flagShortWrite, opts.Write, flagUsageWrite)
+	fmtCmd.Flags().BoolVarP(&opts.List, flagList, flagShortList, opts.List, flagUsageList)
+	fmtCmd.Flags().BoolVarP(&opts.Diff, flagDiff, flagShortDiff, opts.Diff, flagUsageDiff)
+	addConfigFlag(&fmtCmd)
+
+	return fmtCmd
+}
+
+func fmtCmdRunFunc(opts *fmtOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, fmtConfigFlags...); err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			if opts.Write {
+				return fmt.Errorf("can't use --%s with stdin", flagWrite)
+			}
+
+			content, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+
+			return opts.processFile(cmd.OutOrStdout(), stdinFilename, content)
+		}
+
+		filepaths, err := findAbsolutePaths(args)
+		if err != nil {
+			return err
+		}
+
+		for _, path := range filepaths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			if err = opts.processFile(cmd.OutOrStdout(), path, content); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func (opts fmtOptions) processFile(out io.Writer, path string, content []byte) error {
+	formatted := formatter.Format(content)
+	changed := !bytes.Equal(content, formatted)
+	name := displayPath(path)
+
+	if opts.List && changed {
+		fmt.Fprintln(out, name)
+	}
+
+	if opts.Write && changed {
+		const readWriteMode = 0600
+		if _, err := fsutil.WriteFile(path, formatted, readWriteMode); err != nil {
+			return fmt.Errorf("couldn't write file: %w", err)
+		}
+	}
+
+	if opts.Diff && changed {
+		diff := difflib.UnifiedDiff{
+			A:        splitLines(content),
+			B:        splitLines(formatted),
+			FromFile: name + ".orig",
+			ToFile:   name,
+			Context:  3, // nolint: gomnd
+		}
+
+		fmt.Fprintf(out, "diff -u %s.orig %s\n", name, name)
+
+		if err := difflib.WriteUnifiedDiff(out, diff); err != nil {
+			return fmt.Errorf("couldn't write diff: %w", err)
+		}
+	}
+
+	if !opts.List && !opts.Write && !opts.Diff {
+		if _, err := out.Write(formatted); err != nil {
+			return fmt.Errorf("couldn't write to output: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// splitLines splits the content into lines which all end with a newline.
+func splitLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
@@ -0,0 +1,96 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+)
+
+const (
+	unformattedPUML = "@StartUML Example\nalt\nBob->Alice:hello\nend\n@enduml"
+	formattedPUML   = "@startuml Example\nalt\n  Bob -> Alice : hello\nend\n@enduml\n"
+)
+
+func Test_RunFmtCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+
+	testcases := []struct {
+		args           []string
+		expectedOutput string
+		expectedFile   string
+	}{
+		{
+			args:           []string{inputFile},
+			expectedOutput: formattedPUML,
+			expectedFile:   unformattedPUML,
+		},
+		{
+			args:           []string{"-l", inputFile},
+			expectedOutput: inputFile + "\n",
+			expectedFile:   unformattedPUML,
+		},
+		{
+			args: []string{"-d", inputFile},
+			expectedOutput: "diff -u " + inputFile + ".orig " + inputFile + "\n" +
+				"--- " + inputFile + ".orig\n" +
+				"+++ " + inputFile + "\n" +
+				"@@ -1,5 +1,5 @@\n" +
+				"-@StartUML Example\n" +
+				"+@startuml Example\n" +
+				" alt\n" +
+				"-Bob->Alice:hello\n" +
+				"+  Bob -> Alice : hello\n" +
+				" end\n" +
+				" @enduml\n",
+			expectedFile: unformattedPUML,
+		},
+		{
+			args:           []string{"-w", inputFile},
+			expectedOutput: "",
+			expectedFile:   formattedPUML,
+		},
+	}
+
+	for _, tc := range testcases {
+		err := os.WriteFile(inputFile, []byte(unformattedPUML), 0600)
+		require.Nil(t, err)
+
+		cmd := internal.CreateFmtCmd()
+		cmd.SetArgs(tc.args)
+
+		var stdout, stderr bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+
+		err = cmd.Execute()
+		assert.Nil(t, err)
+		assert.Empty(t, stderr.String())
+		assert.Equal(t, tc.expectedOutput, stdout.String())
+
+		actualFile, err := os.ReadFile(inputFile)
+		require.Nil(t, err)
+		assert.Equal(t, tc.expectedFile, string(actualFile))
+	}
+}
+
+func Test_RunFmtCommandStdin(t *testing.T) {
+	cmd := internal.CreateFmtCmd()
+	cmd.SetIn(bytes.NewBufferString(unformattedPUML))
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+	assert.Nil(t, err)
+	assert.Empty(t, stderr.String())
+	assert.Equal(t, formattedPUML, stdout.String())
+}
@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// CreateGenCmd creates the gen subcommand, which groups the commands
+// that generate Plant UML diagrams from Go source code, like the one created by CreateGenClassCmd.
+// The generated diagrams are written to stdout, to be piped into the build command or saved to a file.
+func CreateGenCmd() cobra.Command {
+	genCmd := cobra.Command{
+		Use:   "gen",
+		Short: "Generates Plant UML diagrams from Go source code",
+		Long: `Generates Plant UML diagrams from Go source code.
+
+The generated diagrams are written to stdout, to be piped into the build command
+or saved to a file which can be served.`,
+		Example: `  gopuml gen class ./internal/... | gopuml build --style link`,
+	}
+
+	return genCmd
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"go/types"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const flagUnexported = "unexported"
+
+type genClassOptions struct {
+	Unexported bool
+}
+
+const flagUsageUnexported = `include unexported types, fields and methods
+ `
+
+// genClassConfigFlags are the flags of gen class which are set from the environment or the configuration.
+var genClassConfigFlags = []string{flagUnexported}
+
+// CreateGenClassCmd creates the gen class subcommand.
+// The command loads the Go packages matching the patterns and generates a class diagram
+// of their structs and interfaces.
+func CreateGenClassCmd() cobra.Command {
+	opts := genClassOptions{}
+
+	classCmd := cobra.Command{
+		Use:   "class [packages]",
+		Short: "Generates a class diagram of Go packages",
+		Long: `Generates a class diagram of the structs and interfaces in Go packages.
+
+Structs and interfaces are drawn as classes with their fields and methods,
+embedding is drawn as inheritance, implementation of an interface as realization
+and fields with types in the diagram as associations.`,
+		Example: `  gopuml gen class ./pkg/...
+  gopuml gen class --unexported ./internal/generator > generator.puml`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: genClassCmdRunFunc(&opts),
+	}
+
+	classCmd.Flags().BoolVar(&opts.Unexported, flagUnexported, opts.Unexported, flagUsageUnexported)
+	addConfigFlag(&classCmd)
+
+	return classCmd
+}
+
+func genClassCmdRunFunc(opts *genClassOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, genClassConfigFlags...); err != nil {
+			return err
+		}
+
+		pkgs, err := godiagram.LoadPackages("", args)
+		if err != nil {
+			return err
+		}
+
+		typesPkgs := make([]*types.Package, 0, len(pkgs))
+
+		for _, pkg := range pkgs {
+			typesPkgs = append(typesPkgs, pkg.Types)
+		}
+
+		content := godiagram.Class(typesPkgs, godiagram.ClassOptions{Unexported: opts.Unexported})
+
+		_, err = cmd.OutOrStdout().Write(content)
+
+		return err
+	}
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/fsutil"
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+type genCommentsOptions struct {
+	OutDir string
+}
+
+const flagUsageCommentsOutDir = `the directory to write the Plant UML files to,
+they are written next to the Go files by default
+ `
+
+// genCommentsConfigFlags are the flags of gen comments which are set from the environment or the configuration.
+var genCommentsConfigFlags = []string{flagOutDir}
+
+// CreateGenCommentsCmd creates the gen comments subcommand.
+// The command finds Plant UML diagrams in comments in the Go files of the packages matching the patterns
+// and writes them to Plant UML files.
+func CreateGenCommentsCmd() cobra.Command {
+	opts := genCommentsOptions{}
+
+	commentsCmd := cobra.Command{
+		Use:   "comments [packages]",
+		Short: "Extracts Plant UML diagrams in Go comments into Plant UML files",
+		Long: `Extracts Plant UML diagrams in the comments of Go files into Plant UML files.
+
+A diagram is either a comment block starting with @startuml and ending with @enduml:
+  // @startuml Request Flow
+  // Client -> Server : request
+  // @enduml
+or a comment block starting with a //puml:<name> marker, where the rest of the block is the diagram:
+  //puml:request-flow
+  // Client -> Server : request
+
+The files are named after the diagrams and start with a comment with the origin of the diagram,
+so the build command reports errors on the lines in the Go files. The packages default to ./...`,
+		Example: `  gopuml gen comments ./...
+  gopuml gen comments --out-dir docs/diagrams ./internal/... && gopuml build docs/diagrams`,
+		RunE: genCommentsCmdRunFunc(&opts),
+	}
+
+	commentsCmd.Flags().StringVar(&opts.OutDir, flagOutDir, opts.OutDir, flagUsageCommentsOutDir)
+	addConfigFlag(&commentsCmd)
+
+	return commentsCmd
+}
+
+func genCommentsCmdRunFunc(opts *genCommentsOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, genCommentsConfigFlags...); err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+
+		goFiles, err := godiagram.LoadFiles("", args)
+		if err != nil {
+			return err
+		}
+
+		usedPaths := make(map[string]bool)
+
+		for _, goFile := range goFiles {
+			content, err := os.ReadFile(goFile)
+			if err != nil {
+				return err
+			}
+
+			diagrams, err := godiagram.FindCommentDiagrams(goFile, content)
+			if err != nil {
+				return err
+			}
+
+			for _, diagram := range diagrams {
+				if err = writeCommentDiagram(cmd, opts.OutDir, diagram, usedPaths); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// writeCommentDiagram writes the diagram to a file named after the diagram,
+// or after the Go file and line when the diagram has no name or the name is already used.
+func writeCommentDiagram(cmd *cobra.Command, outDir string, diagram godiagram.CommentDiagram, usedPaths map[string]bool) error {
+	dir := outDir
+	if dir == "" {
+		dir = filepath.Dir(diagram.Filepath)
+	}
+
+	fallbackName := fmt.Sprintf("%s-%d", strings.TrimSuffix(filepath.Base(diagram.Filepath), ".go"), diagram.Line)
+
+	name := slug(diagram.Name)
+	if name == "" || usedPaths[filepath.Join(dir, name+".puml")] {
+		name = slug(fallbackName)
+	}
+
+	path := filepath.Join(dir, name+".puml")
+	usedPaths[path] = true
+
+	content := diagram.Content(relativeLink(dir, diagram.Filepath))
+
+	const readWriteExecuteMode = 0700
+	if err := os.MkdirAll(dir, readWriteExecuteMode); err != nil {
+		return fmt.Errorf("couldn't create directory: %w", err)
+	}
+
+	const readWriteMode = 0600
+
+	written, err := fsutil.WriteFile(path, content, readWriteMode)
+	if err != nil {
+		return fmt.Errorf("couldn't write file: %w", err)
+	}
+
+	if !written {
+		return nil
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "updated file:", displayPath(path))
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const (
+	flagInternal = "internal"
+	flagAll      = "all"
+	flagDepth    = "depth"
+	flagCollapse = "collapse"
+	flagExclude  = "exclude"
+)
+
+type genDepsOptions struct {
+	Internal bool
+	All      bool
+	Depth    int
+	Collapse []string
+	Exclude  []string
+}
+
+const flagUsageInternal = `only include packages in the main module
+ `
+
+const flagUsageAll = `follow the imports of packages outside the main module,
+which are otherwise drawn without their imports
+ `
+
+const flagUsageDepth = `the number of imports to follow from the packages, 0 means no limit
+ `
+
+const flagUsageCollapse = `collapse the packages with the path prefix into one package,
+can be repeated
+ `
+
+const flagUsageExclude = `exclude the packages with a path matching the regular expression,
+can be repeated
+ `
+
+// genDepsConfigFlags are the flags of gen deps which are set from the environment or the configuration.
+var genDepsConfigFlags = []string{flagInternal, flagAll, flagDepth, flagCollapse, flagExclude}
+
+// CreateGenDepsCmd creates the gen deps subcommand.
+// The command loads the Go packages matching the patterns and generates a component diagram
+// of their import graph.
+func CreateGenDepsCmd() cobra.Command {
+	opts := genDepsOptions{}
+
+	depsCmd := cobra.Command{
+		Use:   "deps [packages]",
+		Short: "Generates a component diagram of the imports of Go packages",
+		Long: `Generates a component diagram of the import graph of Go packages.
+
+Packages in the main module, in the standard library and in third-party modules
+are drawn in different colors, and imports which are part of a cycle are highlighted.
+Packages outside the main module are drawn without their imports, unless --all is set.`,
+		Example: `  gopuml gen deps ./...
+  gopuml gen deps --internal --collapse github.com/lonnblad/gopuml/internal ./...
+  gopuml gen deps --depth 1 --exclude '^(fmt|os)$' ./cmd/...`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: genDepsCmdRunFunc(&opts),
+	}
+
+	depsCmd.Flags().BoolVar(&opts.Internal, flagInternal, opts.Internal, flagUsageInternal)
+	depsCmd.Flags().BoolVar(&opts.All, flagAll, opts.All, flagUsageAll)
+	depsCmd.Flags().IntVar(&opts.Depth, flagDepth, opts.Depth, flagUsageDepth)
+	depsCmd.Flags().StringArrayVar(&opts.Collapse, flagCollapse, opts.Collapse, flagUsageCollapse)
+	depsCmd.Flags().StringArrayVar(&opts.Exclude, flagExclude, opts.Exclude, flagUsageExclude)
+	addConfigFlag(&depsCmd)
+
+	return depsCmd
+}
+
+func genDepsCmdRunFunc(opts *genDepsOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, genDepsConfigFlags...); err != nil {
+			return err
+		}
+
+		exclude, err := compileAll(opts.Exclude)
+		if err != nil {
+			return fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+
+		depsOpts := godiagram.DepsOptions{Internal: opts.Internal, All: opts.All, Depth: opts.Depth, Collapse: opts.Collapse, Exclude: exclude}
+
+		pkgs, err := godiagram.LoadImports("", args)
+		if err != nil {
+			return err
+		}
+
+		content := godiagram.Deps(godiagram.NewImportGraph(pkgs), depsOpts)
+
+		_, err = cmd.OutOrStdout().Write(content)
+
+		return err
+	}
+}
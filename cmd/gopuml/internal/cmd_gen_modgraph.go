@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const flagGoMod = "gomod"
+
+type genModGraphOptions struct {
+	GoMod string
+}
+
+const flagUsageGoMod = `the go.mod file of the main module, used to find indirect requirements
+and replaced modules when the input is the output of go mod graph
+ `
+
+// genModGraphConfigFlags are the flags of gen modgraph which are set from the environment or the configuration.
+var genModGraphConfigFlags = []string{flagGoMod}
+
+// CreateGenModGraphCmd creates the gen modgraph subcommand.
+// The command reads a go.mod file or the output of "go mod graph", from a file or stdin,
+// and generates a component diagram of the module requirements.
+func CreateGenModGraphCmd() cobra.Command {
+	opts := genModGraphOptions{}
+
+	modGraphCmd := cobra.Command{
+		Use:   "modgraph [go.mod or go mod graph output]",
+		Short: "Generates a component diagram of Go module requirements",
+		Long: `Generates a component diagram of the requirements of Go modules, with their versions.
+
+The input is either a go.mod file or the output of go mod graph, read from stdin
+when no file is given. Replaced modules, indirect requirements and modules
+required in multiple versions are highlighted.`,
+		Example: `  gopuml gen modgraph go.mod
+  go mod graph | gopuml gen modgraph --gomod go.mod
+  go mod graph | gopuml gen modgraph --gomod go.mod | gopuml build --style link`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: genModGraphCmdRunFunc(&opts),
+	}
+
+	modGraphCmd.Flags().StringVar(&opts.GoMod, flagGoMod, opts.GoMod, flagUsageGoMod)
+	addConfigFlag(&modGraphCmd)
+
+	return modGraphCmd
+}
+
+func genModGraphCmdRunFunc(opts *genModGraphOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, genModGraphConfigFlags...); err != nil {
+			return err
+		}
+
+		var (
+			content []byte
+			err     error
+		)
+
+		if len(args) == 0 || args[0] == "-" {
+			content, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			content, err = os.ReadFile(args[0])
+		}
+
+		if err != nil {
+			return err
+		}
+
+		graph, err := parseModuleGraph(content)
+		if err != nil {
+			return err
+		}
+
+		if opts.GoMod != "" {
+			goModContent, err := os.ReadFile(opts.GoMod)
+			if err != nil {
+				return err
+			}
+
+			goModGraph, err := godiagram.ParseGoMod(goModContent)
+			if err != nil {
+				return err
+			}
+
+			graph.Merge(goModGraph)
+		}
+
+		_, err = cmd.OutOrStdout().Write(godiagram.ModGraph(graph))
+
+		return err
+	}
+}
+
+// parseModuleGraph parses the content as a go.mod file if it has a module directive,
+// and as the output of go mod graph otherwise.
+func parseModuleGraph(content []byte) (godiagram.ModuleGraph, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "module ") || strings.HasPrefix(line, "module\t") {
+			return godiagram.ParseGoMod(content)
+		}
+
+		break
+	}
+
+	return godiagram.ParseModGraph(content)
+}
@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const (
+	defaultSequenceDepth = 3
+
+	flagEntry       = "entry"
+	flagInclude     = "include"
+	flagParticipant = "participant"
+
+	participantPackage = "package"
+	participantType    = "type"
+)
+
+type genSequenceOptions struct {
+	Entry       string
+	Depth       int
+	Include     []string
+	Exclude     []string
+	Participant string
+}
+
+const flagUsageEntry = `the function to start from, like:
+  github.com/lonnblad/gopuml/internal/source.Load
+  github.com/lonnblad/gopuml/internal/render.Renderer.Render
+ `
+
+const flagUsageSequenceDepth = `the number of nested calls to follow from the entry, 0 means no limit
+ `
+
+const flagUsageInclude = `only draw calls to packages with a path matching the regular expression,
+can be repeated, calls to packages in the main module are drawn by default
+ `
+
+const flagUsageSequenceExclude = `don't draw calls to packages with a path matching the regular expression,
+can be repeated
+ `
+
+const flagUsageParticipant = `what the participants in the diagram are
+
+supported participants are:
+  ` + participantPackage + `  the packages of the functions
+  ` + participantType + `     the receiver types of methods and the packages of functions
+ `
+
+// genSequenceConfigFlags are the flags of gen sequence which are set from the environment or the configuration.
+var genSequenceConfigFlags = []string{flagEntry, flagDepth, flagInclude, flagExclude, flagParticipant}
+
+// CreateGenSequenceCmd creates the gen sequence subcommand.
+// The command loads the Go packages matching the patterns, builds their SSA form
+// and generates a sequence diagram of the static call graph from the entry function.
+func CreateGenSequenceCmd() cobra.Command {
+	opts := genSequenceOptions{
+		Depth:       defaultSequenceDepth,
+		Participant: participantPackage,
+	}
+
+	sequenceCmd := cobra.Command{
+		Use:   "sequence --entry <function> [packages]",
+		Short: "Generates a sequence diagram of the calls from a Go function",
+		Long: `Generates a sequence diagram of the static call graph from a Go function.
+
+Packages, or receiver types, are participants and calls are messages. Only the calls
+in functions of the loaded packages are followed, the packages default to ./...
+A call through an interface is drawn as a message to the interface,
+with a note listing the implementations in the loaded packages. Every call activates
+the called participant until it returns, except calls started with go.
+The calls of a function are drawn the first time it's called, later calls refer to them.`,
+		Example: `  gopuml gen sequence --entry github.com/lonnblad/gopuml/internal/source.Load
+  gopuml gen sequence --entry example.com/app/server.Server.Handle --participant type --depth 2 ./...`,
+		RunE: genSequenceCmdRunFunc(&opts),
+	}
+
+	sequenceCmd.Flags().StringVar(&opts.Entry, flagEntry, opts.Entry, flagUsageEntry)
+	sequenceCmd.Flags().IntVar(&opts.Depth, flagDepth, opts.Depth, flagUsageSequenceDepth)
+	sequenceCmd.Flags().StringArrayVar(&opts.Include, flagInclude, opts.Include, flagUsageInclude)
+	sequenceCmd.Flags().StringArrayVar(&opts.Exclude, flagExclude, opts.Exclude, flagUsageSequenceExclude)
+	sequenceCmd.Flags().StringVar(&opts.Participant, flagParticipant, opts.Participant, flagUsageParticipant)
+	addConfigFlag(&sequenceCmd)
+
+	return sequenceCmd
+}
+
+func genSequenceCmdRunFunc(opts *genSequenceOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, genSequenceConfigFlags...); err != nil {
+			return err
+		}
+
+		if opts.Entry == "" {
+			return errors.New("missing entry, use --" + flagEntry)
+		}
+
+		if opts.Participant != participantPackage && opts.Participant != participantType {
+			return fmt.Errorf("unsupported participant: [%s]", opts.Participant)
+		}
+
+		sequenceOpts := godiagram.SequenceOptions{
+			Entry:  opts.Entry,
+			Depth:  opts.Depth,
+			ByType: opts.Participant == participantType,
+		}
+
+		var err error
+
+		if sequenceOpts.Include, err = compileAll(opts.Include); err != nil {
+			return fmt.Errorf("invalid include pattern: %w", err)
+		}
+
+		if sequenceOpts.Exclude, err = compileAll(opts.Exclude); err != nil {
+			return fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+
+		pkgs, err := godiagram.LoadPackages("", args)
+		if err != nil {
+			return err
+		}
+
+		content, err := godiagram.Sequence(pkgs, sequenceOpts)
+		if err != nil {
+			return err
+		}
+
+		_, err = cmd.OutOrStdout().Write(content)
+
+		return err
+	}
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	result := make([]*regexp.Regexp, 0, len(exprs))
+
+	for _, expr := range exprs {
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, compiled)
+	}
+
+	return result, nil
+}
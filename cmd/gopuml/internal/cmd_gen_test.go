@@ -0,0 +1,161 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+)
+
+func Test_RunGenClassCommand(t *testing.T) {
+	stdout, _, err := executeGenCmd("class", "../../../internal/diagnostic")
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, "@startuml\n")
+	assert.Contains(t, stdout, `package "github.com/lonnblad/gopuml/internal/diagnostic" {`)
+	assert.Contains(t, stdout, `  class "Diagnostic" as github_com_lonnblad_gopuml_internal_diagnostic_Diagnostic {`)
+	assert.Contains(t, stdout, "    +String() string\n")
+	assert.Contains(t, stdout, "@enduml\n")
+
+	_, _, err = executeGenCmd("class", "./does-not-exist")
+	assert.NotNil(t, err)
+}
+
+func Test_RunGenCommentsCommand(t *testing.T) {
+	moduleDir := t.TempDir()
+	outDir := t.TempDir()
+
+	goFile := "package server\n\n" +
+		"// @startuml Request Flow\n" +
+		"// Client -> Server : request\n" +
+		"// @enduml\n" +
+		"func Handle() {}\n"
+
+	require.Nil(t, os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte("module example.com/app\n\ngo 1.20\n"), 0600))
+	require.Nil(t, os.WriteFile(filepath.Join(moduleDir, "server.go"), []byte(goFile), 0600))
+
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir(moduleDir))
+	t.Cleanup(func() { os.Chdir(wd) }) // nolint: errcheck
+
+	stdout, _, err := executeGenCmd("comments", "--out-dir", outDir)
+	require.Nil(t, err)
+
+	path := filepath.Join(outDir, "request-flow.puml")
+	assert.Equal(t, "updated file: "+path+"\n", stdout)
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	expected := "' gopuml:origin=" + filepath.ToSlash(relative(t, outDir, filepath.Join(moduleDir, "server.go"))) + ":3\n" +
+		"@startuml Request Flow\n" +
+		"Client -> Server : request\n" +
+		"@enduml\n"
+	assert.Equal(t, expected, string(content))
+
+	stdout, _, err = executeGenCmd("comments", "--out-dir", outDir)
+	require.Nil(t, err)
+	assert.Empty(t, stdout)
+}
+
+func relative(t *testing.T, dir, path string) string {
+	t.Helper()
+
+	rel, err := filepath.Rel(dir, path)
+	require.Nil(t, err)
+
+	return rel
+}
+
+func Test_RunGenDepsCommand(t *testing.T) {
+	stdout, _, err := executeGenCmd("deps", "--internal", "../../../internal/docs")
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, `component "github.com/lonnblad/gopuml/internal/docs" as github_com_lonnblad_gopuml_internal_docs #LightBlue`)
+	assert.Contains(t, stdout, "github_com_lonnblad_gopuml_internal_docs --> github_com_lonnblad_gopuml\n")
+	assert.NotContains(t, stdout, `"regexp"`)
+
+	stdout, _, err = executeGenCmd("deps", "../../../internal/docs")
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, `component "regexp" as regexp #LightGray`)
+	assert.NotContains(t, stdout, `"regexp/syntax"`)
+
+	stdout, _, err = executeGenCmd("deps", "--all", "../../../internal/docs")
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, "regexp --> regexp_syntax\n")
+
+	_, _, err = executeGenCmd("deps", "--exclude", "(", "../../../internal/docs")
+	assert.NotNil(t, err)
+}
+
+func Test_RunGenModGraphCommand(t *testing.T) {
+	stdout, _, err := executeGenCmd("modgraph", "../../../go.mod")
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, `component "github.com/lonnblad/gopuml" as github_com_lonnblad_gopuml #LightBlue`)
+	assert.Contains(t, stdout, "github_com_lonnblad_gopuml --> github_com_spf13_cobra_v1_7_0\n")
+	assert.Contains(t, stdout, "github_com_lonnblad_gopuml ..> github_com_inconshreveable_mousetrap_v1_1_0\n")
+
+	graph := "example.com/app example.com/a@v1.0.0\nexample.com/a@v1.0.0 example.com/b@v1.1.0\n"
+
+	cmd := internal.CreateGenModGraphCmd()
+	cmd.SetArgs([]string{"--gomod", "../../../go.mod"})
+	cmd.SetIn(bytes.NewBufferString(graph))
+
+	var graphOut bytes.Buffer
+
+	cmd.SetOut(&graphOut)
+
+	require.Nil(t, cmd.Execute())
+	assert.Contains(t, graphOut.String(), "example_com_a_v1_0_0 --> example_com_b_v1_1_0\n")
+
+	_, _, err = executeGenCmd("modgraph", "../../../README.md")
+	assert.NotNil(t, err)
+}
+
+func Test_RunGenSequenceCommand(t *testing.T) {
+	stdout, _, err := executeGenCmd(
+		"sequence", "--entry", "github.com/lonnblad/gopuml/internal/formatter.Format", "../../../internal/...",
+	)
+	require.Nil(t, err)
+
+	assert.Contains(t, stdout, `participant "github.com/lonnblad/gopuml/internal/formatter" as github_com_lonnblad_gopuml_internal_formatter`)
+	assert.Contains(t, stdout, "github_com_lonnblad_gopuml_internal_formatter -> github_com_lonnblad_gopuml_internal_parser : Parse()\n")
+
+	_, _, err = executeGenCmd("sequence", "../../../internal/formatter")
+	assert.NotNil(t, err)
+
+	_, _, err = executeGenCmd(
+		"sequence", "--entry", "github.com/lonnblad/gopuml/internal/formatter.Format", "--participant", "x", "../../../internal/formatter",
+	)
+	assert.NotNil(t, err)
+}
+
+func executeGenCmd(args ...string) (string, string, error) {
+	cmd := internal.CreateGenCmd()
+	classCmd := internal.CreateGenClassCmd()
+	commentsCmd := internal.CreateGenCommentsCmd()
+	depsCmd := internal.CreateGenDepsCmd()
+	modGraphCmd := internal.CreateGenModGraphCmd()
+	sequenceCmd := internal.CreateGenSequenceCmd()
+
+	cmd.AddCommand(&classCmd, &commentsCmd, &depsCmd, &modGraphCmd, &sequenceCmd)
+	cmd.SetArgs(args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/githook"
+)
+
+// CreateHookCmd creates the hook subcommand, which groups the commands
+// that manage the git pre-commit hook, like the one created by CreateHookInstallCmd.
+func CreateHookCmd() cobra.Command {
+	hookCmd := cobra.Command{
+		Use:   "hook",
+		Short: "Manages the git pre-commit hook",
+		Long: `Manages the git pre-commit hook, which checks the staged Plant UML files
+with fmt, lint and build --check before every commit.
+
+An existing pre-commit hook is kept and run before the checks of gopuml.`,
+		Example: `  gopuml hook install
+  gopuml hook uninstall`,
+	}
+
+	return hookCmd
+}
+
+// CreateHookInstallCmd creates the hook install subcommand.
+func CreateHookInstallCmd() cobra.Command {
+	installCmd := cobra.Command{
+		Use:   "install",
+		Short: "Installs the git pre-commit hook",
+		Long: `Installs the git pre-commit hook in the git repository of the working directory.
+
+An existing pre-commit hook is renamed to ` + githook.ChainedName + ` and run before
+the checks of gopuml, it's restored by gopuml hook uninstall.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hooksDir, err := githook.HooksDir(".")
+			if err != nil {
+				return err
+			}
+
+			path := displayPath(filepath.Join(hooksDir, githook.Name))
+
+			installed, err := githook.Install(hooksDir)
+			if err != nil {
+				return err
+			}
+
+			if !installed {
+				cmd.Println("the pre-commit hook is already installed: " + path)
+				return nil
+			}
+
+			cmd.Println("installed the pre-commit hook: " + path)
+
+			if _, err = os.Stat(filepath.Join(hooksDir, githook.ChainedName)); err == nil {
+				cmd.Println("the existing hook is run first: " + displayPath(filepath.Join(hooksDir, githook.ChainedName)))
+			}
+
+			return nil
+		},
+	}
+
+	return installCmd
+}
+
+// CreateHookUninstallCmd creates the hook uninstall subcommand.
+func CreateHookUninstallCmd() cobra.Command {
+	uninstallCmd := cobra.Command{
+		Use:   "uninstall",
+		Short: "Uninstalls the git pre-commit hook",
+		Long: `Uninstalls the git pre-commit hook from the git repository of the working directory,
+and restores the pre-commit hook which existed before it was installed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hooksDir, err := githook.HooksDir(".")
+			if err != nil {
+				return err
+			}
+
+			path := displayPath(filepath.Join(hooksDir, githook.Name))
+
+			if err = githook.Uninstall(hooksDir); errors.Is(err, githook.ErrNotInstalled) {
+				cmd.Println("the pre-commit hook isn't installed: " + path)
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			cmd.Println("uninstalled the pre-commit hook: " + path)
+
+			return nil
+		},
+	}
+
+	return uninstallCmd
+}
@@ -0,0 +1,367 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/changes"
+	"github.com/lonnblad/gopuml/internal/config"
+	"github.com/lonnblad/gopuml/internal/formatter"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+)
+
+const (
+	flagAutoStage = "auto-stage"
+	flagSkip      = "skip"
+)
+
+// The checks run by the hook.
+const (
+	checkFmt   = "fmt"
+	checkLint  = "lint"
+	checkBuild = "build"
+)
+
+type hookRunOptions struct {
+	AutoStage bool
+	Skip      []string
+}
+
+const flagUsageAutoStage = `write and stage unformatted files and outdated outputs, instead of failing
+ `
+
+const flagUsageSkip = `the checks to skip, any of: ` + checkFmt + `, ` + checkLint + `, ` + checkBuild + `
+ `
+
+// hookRunConfigFlags are the flags of hook run which are set from the environment or the configuration.
+var hookRunConfigFlags = []string{flagAutoStage, flagSkip}
+
+// CreateHookRunCmd creates the hook run subcommand, which is run by the installed pre-commit hook.
+func CreateHookRunCmd() cobra.Command {
+	opts := hookRunOptions{}
+
+	runCmd := cobra.Command{
+		Use:   "run",
+		Short: "Checks the staged Plant UML files",
+		Long: `Checks the staged Plant UML files, it's run by the installed pre-commit hook.
+
+The staged files are checked with fmt and lint, and the staged files and the files
+which include them are checked with build --check. The files are checked as they are
+staged, which is what will be committed, not as they are in the working tree.
+
+With --auto-stage, unformatted files are formatted and outdated outputs are rebuilt,
+and they are staged. Files with unstaged changes aren't formatted, since staging them
+would stage the unstaged changes as well.
+
+The flags can be set in the hook section of ` + config.Filename + `:
+  hook:
+    autoStage: true
+    skip: [lint]`,
+		Example: `  gopuml hook run
+  gopuml hook run --auto-stage --skip lint`,
+		Args: cobra.NoArgs,
+		RunE: hookRunCmdRunFunc(&opts),
+	}
+
+	runCmd.Flags().BoolVar(&opts.AutoStage, flagAutoStage, opts.AutoStage, flagUsageAutoStage)
+	runCmd.Flags().StringSliceVar(&opts.Skip, flagSkip, opts.Skip, flagUsageSkip)
+	addConfigFlag(&runCmd)
+
+	return runCmd
+}
+
+// hookCheck is the result of a check which failed.
+type hookCheck struct {
+	name   string
+	output string
+}
+
+func hookRunCmdRunFunc(opts *hookRunOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err = applyDefaults(cmd, cfg, hookRunConfigFlags...); err != nil {
+			return err
+		}
+
+		skip := make(map[string]bool, len(opts.Skip))
+
+		for _, name := range opts.Skip {
+			if name != checkFmt && name != checkLint && name != checkBuild {
+				return fmt.Errorf("unsupported check: [%s]", name)
+			}
+
+			skip[name] = true
+		}
+
+		staged, err := changes.Staged(".")
+		if err != nil {
+			return err
+		}
+
+		files := plantUMLFiles(staged)
+
+		var failed []hookCheck
+
+		if !skip[checkFmt] && len(files) > 0 {
+			if output, err := opts.checkFmt(cmd, files); err != nil {
+				return err
+			} else if output != "" {
+				failed = append(failed, hookCheck{name: checkFmt, output: output})
+			}
+		}
+
+		if !skip[checkLint] && len(files) > 0 {
+			args := append(configArgs(cmd), "--"+flagStaged)
+			if output := runSubcommand(cmd, CreateLintCmd(), append(args, files...)); output != "" {
+				failed = append(failed, hookCheck{name: checkLint, output: output})
+			}
+		}
+
+		if !skip[checkBuild] {
+			if output, err := opts.checkBuild(cmd, staged); err != nil {
+				return interrupted(cmd, err)
+			} else if output != "" {
+				failed = append(failed, hookCheck{name: checkBuild, output: output})
+			}
+		}
+
+		if err = interrupted(cmd, cmd.Context().Err()); err != nil {
+			return err
+		}
+
+		return reportHookChecks(cmd, failed)
+	}
+}
+
+// plantUMLFiles returns the Plant UML files of the paths, relative to the working directory when possible.
+func plantUMLFiles(paths []string) []string {
+	var files []string
+
+	for _, path := range paths {
+		if plantUMLExtensions[filepath.Ext(path)] {
+			files = append(files, displayPath(path))
+		}
+	}
+
+	return files
+}
+
+// checkFmt returns a line for every staged file which isn't formatted,
+// or formats and stages the files with auto stage, unless they have unstaged changes.
+func (opts hookRunOptions) checkFmt(cmd *cobra.Command, files []string) (string, error) {
+	var output strings.Builder
+
+	unstaged := make(map[string]bool)
+
+	if opts.AutoStage {
+		paths, err := changes.Unstaged(".", files...)
+		if err != nil {
+			return "", err
+		}
+
+		for _, path := range paths {
+			unstaged[displayPath(path)] = true
+		}
+	}
+
+	for _, path := range files {
+		content, err := changes.ReadStaged(path)
+		if err != nil {
+			return "", err
+		}
+
+		formatted := formatter.Format(content)
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+
+		if !opts.AutoStage {
+			fmt.Fprintf(&output, "%s: not formatted, run: gopuml fmt -w %s\n", path, path)
+			continue
+		}
+
+		if unstaged[path] {
+			fmt.Fprintf(&output, "%s: not formatted and has unstaged changes, run: gopuml fmt -w %s and stage it\n", path, path)
+			continue
+		}
+
+		const readWriteMode = 0600
+		if _, err = fsutil.WriteFile(path, formatted, readWriteMode); err != nil {
+			return "", fmt.Errorf("couldn't write file: %w", err)
+		}
+
+		if err = stageFiles(cmd, path); err != nil {
+			return "", err
+		}
+	}
+
+	return output.String(), nil
+}
+
+// checkBuild builds the staged Plant UML files, and the files which include any staged file, with --check,
+// or rebuilds and stages the outputs with auto stage.
+func (opts hookRunOptions) checkBuild(cmd *cobra.Command, staged []string) (string, error) {
+	documents, err := findDocuments([]string{"."}, plantUMLExtensions)
+	if err != nil {
+		return "", err
+	}
+
+	files, err := changes.Affected(documents, staged)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	for i, path := range files {
+		files[i] = displayPath(path)
+	}
+
+	args := append(configArgs(cmd), "--"+flagStaged)
+
+	if !opts.AutoStage {
+		args = append(args, "--"+flagCheck)
+		return runSubcommand(cmd, CreateBuildCmd(), append(args, files...)), cmd.Context().Err()
+	}
+
+	report, err := os.CreateTemp("", "gopuml-report-*.json")
+	if err != nil {
+		return "", err
+	}
+
+	report.Close()
+	defer os.Remove(report.Name())
+
+	args = append(args, "--"+flagReport, report.Name())
+	output := runSubcommand(cmd, CreateBuildCmd(), append(args, files...))
+
+	if err = cmd.Context().Err(); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(report.Name())
+	if err != nil {
+		return "", err
+	}
+
+	// The build writes its report unless it fails before building, like with an invalid configuration.
+	if len(content) == 0 {
+		if output == "" {
+			return "", errors.New("the build didn't write its report")
+		}
+
+		return output, nil
+	}
+
+	var built buildReport
+	if err = json.Unmarshal(content, &built); err != nil {
+		return "", fmt.Errorf("couldn't read the build report: %w", err)
+	}
+
+	// The outputs which were up to date are staged as well, in case they were changed but not staged.
+	var outputs []string
+
+	for _, entry := range built.Diagrams {
+		if entry.Output != "" && entry.Error == "" {
+			outputs = append(outputs, entry.Output)
+		}
+	}
+
+	if err = changes.Stage(".", outputs...); err != nil {
+		return "", err
+	}
+
+	for _, entry := range built.Diagrams {
+		if entry.Written {
+			cmd.PrintErrln("staged file: " + entry.Output)
+		}
+	}
+
+	return output, nil
+}
+
+// configArgs returns the arguments which pass the configuration file of the command on to a subcommand.
+func configArgs(cmd *cobra.Command) []string {
+	if configPath, _ := cmd.Flags().GetString(flagConfig); configPath != "" { // nolint: errcheck
+		return []string{"--" + flagConfig, configPath}
+	}
+
+	return nil
+}
+
+// runSubcommand runs the command with the files and returns its output and error when it fails,
+// or an empty string when it succeeds.
+func runSubcommand(cmd *cobra.Command, subcommand cobra.Command, args []string) string {
+	var output bytes.Buffer
+
+	subcommand.SetArgs(args)
+	subcommand.SetIn(&bytes.Buffer{})
+	subcommand.SetOut(&output)
+	subcommand.SetErr(&output)
+	subcommand.SilenceErrors = true
+	subcommand.SilenceUsage = true
+
+	err := subcommand.ExecuteContext(cmd.Context())
+	if err == nil {
+		return ""
+	}
+
+	output.WriteString(err.Error() + "\n")
+
+	return output.String()
+}
+
+// stageFiles stages the files and writes them to stderr.
+func stageFiles(cmd *cobra.Command, paths ...string) error {
+	if err := changes.Stage(".", paths...); err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		cmd.PrintErrln("staged file: " + path)
+	}
+
+	return nil
+}
+
+// reportHookChecks writes a summary of the failed checks to stderr
+// and returns an error if there were any.
+func reportHookChecks(cmd *cobra.Command, failed []hookCheck) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	cmd.PrintErrln("gopuml found problems in the staged Plant UML files:")
+
+	for _, check := range failed {
+		cmd.PrintErrln()
+		cmd.PrintErrln(check.name + ":")
+
+		for _, line := range strings.Split(strings.TrimSuffix(check.output, "\n"), "\n") {
+			cmd.PrintErrln("  " + line)
+		}
+	}
+
+	cmd.PrintErrln()
+	cmd.PrintErrln("fix the problems and stage the files, or set hook.autoStage in " + config.Filename + " to fix what can be fixed,")
+	cmd.PrintErrln("the hook can be skipped with: git commit --no-verify")
+
+	// The summary is the error report, cobra shouldn't print the error or the usage.
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	return fmt.Errorf("%d check(s) failed", len(failed))
+}
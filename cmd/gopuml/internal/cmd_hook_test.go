@@ -0,0 +1,166 @@
+package internal_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+func Test_RunHookInstallAndUninstallCommand(t *testing.T) {
+	dir := newGitRepository(t)
+	chdir(t, dir)
+
+	hook := filepath.Join(".git", "hooks", "pre-commit")
+	existing := "#!/bin/sh\nexit 0\n"
+
+	require.Nil(t, os.WriteFile(hook, []byte(existing), 0700)) // nolint: gosec
+
+	stdout, _, err := executeHookCmd("install")
+	require.Nil(t, err)
+	assert.Equal(t, "installed the pre-commit hook: "+hook+"\nthe existing hook is run first: "+hook+".before-gopuml\n", stdout)
+
+	content, err := os.ReadFile(hook)
+	require.Nil(t, err)
+	assert.Contains(t, string(content), "exec gopuml hook run\n")
+
+	content, err = os.ReadFile(hook + ".before-gopuml")
+	require.Nil(t, err)
+	assert.Equal(t, existing, string(content))
+
+	stdout, _, err = executeHookCmd("install")
+	require.Nil(t, err)
+	assert.Equal(t, "the pre-commit hook is already installed: "+hook+"\n", stdout)
+
+	stdout, _, err = executeHookCmd("uninstall")
+	require.Nil(t, err)
+	assert.Equal(t, "uninstalled the pre-commit hook: "+hook+"\n", stdout)
+
+	content, err = os.ReadFile(hook)
+	require.Nil(t, err)
+	assert.Equal(t, existing, string(content))
+	assert.NoFileExists(t, hook+".before-gopuml")
+
+	stdout, _, err = executeHookCmd("uninstall")
+	require.Nil(t, err)
+	assert.Equal(t, "the pre-commit hook isn't installed: "+hook+"\n", stdout)
+
+	chdir(t, t.TempDir())
+
+	_, _, err = executeHookCmd("install")
+	assert.NotNil(t, err)
+}
+
+func Test_RunHookRunCommand(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture("txt", []byte("rendered"))
+
+	dir := newGitRepository(t)
+	chdir(t, dir)
+
+	t.Setenv("GOPUML_SERVER", server.URL)
+	t.Setenv("GOPUML_FORMAT", "txt")
+
+	require.Nil(t, os.WriteFile("README.md", []byte("# Diagrams\n"), 0600))
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-m", "initial")
+
+	_, stderr, err := executeHookCmd("run")
+	require.Nil(t, err)
+	assert.Empty(t, stderr)
+
+	require.Nil(t, os.WriteFile("a.puml", []byte(unformattedPUML), 0600))
+	runGit(t, dir, "add", "a.puml")
+
+	_, stderr, err = executeHookCmd("run")
+	assert.EqualError(t, err, "2 check(s) failed")
+	assert.Equal(t, "gopuml found problems in the staged Plant UML files:\n"+
+		"\n"+
+		"fmt:\n"+
+		"  a.puml: not formatted, run: gopuml fmt -w a.puml\n"+
+		"\n"+
+		"build:\n"+
+		"  a.txt: out of date\n"+
+		"  found 0 diagram error(s) and 1 file(s) out of date\n"+
+		"\n"+
+		"fix the problems and stage the files, or set hook.autoStage in .gopuml.yaml to fix what can be fixed,\n"+
+		"the hook can be skipped with: git commit --no-verify\n", stderr)
+
+	_, stderr, err = executeHookCmd("run", "--skip", "fmt,build")
+	require.Nil(t, err)
+	assert.Empty(t, stderr)
+
+	// The staged content is checked, formatting the file without staging it doesn't fix the commit.
+	require.Nil(t, os.WriteFile("a.puml", []byte(formattedPUML), 0600))
+
+	_, stderr, err = executeHookCmd("run", "--skip", "build")
+	assert.EqualError(t, err, "1 check(s) failed")
+	assert.Contains(t, stderr, "fmt:\n  a.puml: not formatted, run: gopuml fmt -w a.puml\n")
+
+	// Files with unstaged changes aren't staged, which would stage the unstaged changes.
+	_, stderr, err = executeHookCmd("run", "--skip", "build", "--auto-stage")
+	assert.EqualError(t, err, "1 check(s) failed")
+	assert.Contains(t, stderr, "fmt:\n  a.puml: not formatted and has unstaged changes, run: gopuml fmt -w a.puml and stage it\n")
+
+	runGit(t, dir, "checkout", "--", "a.puml")
+
+	require.Nil(t, os.WriteFile(".gopuml.yaml", []byte("hook:\n  autoStage: true\n"), 0600))
+
+	_, stderr, err = executeHookCmd("run")
+	require.Nil(t, err)
+	assert.Equal(t, "staged file: a.puml\nstaged file: a.txt\n", stderr)
+
+	content, err := os.ReadFile("a.puml")
+	require.Nil(t, err)
+	assert.Equal(t, formattedPUML, string(content))
+
+	staged := runGit(t, dir, "diff", "--cached", "--name-only")
+	assert.Equal(t, []string{"a.puml", "a.txt"}, strings.Fields(staged))
+
+	require.Nil(t, os.WriteFile("b.puml", []byte("@startuml\nBob -> Alice\n@enduml\n"), 0600))
+	runGit(t, dir, "add", "b.puml")
+
+	_, stderr, err = executeHookCmd("run", "--skip", "build")
+	assert.EqualError(t, err, "1 check(s) failed")
+	assert.Contains(t, stderr, "lint:\n  b.puml:1: diagram has no name after @startuml")
+
+	// A build which fails to render is reported, instead of staging nothing.
+	server.FailNext(plantumltest.Failure{StatusCode: http.StatusNotFound})
+
+	_, stderr, err = executeHookCmd("run", "--skip", "fmt,lint")
+	assert.EqualError(t, err, "1 check(s) failed")
+	assert.Contains(t, stderr, "build:\n  wrong status code 404 Not Found")
+	assert.NotContains(t, stderr, "staged file")
+
+	_, _, err = executeHookCmd("run", "--skip", "test")
+	assert.EqualError(t, err, "unsupported check: [test]")
+}
+
+func executeHookCmd(args ...string) (string, string, error) {
+	cmd := internal.CreateHookCmd()
+	installCmd := internal.CreateHookInstallCmd()
+	runCmd := internal.CreateHookRunCmd()
+	uninstallCmd := internal.CreateHookUninstallCmd()
+
+	cmd.AddCommand(&installCmd, &runCmd, &uninstallCmd)
+	cmd.SetArgs(args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
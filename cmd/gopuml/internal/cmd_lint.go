@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/config"
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/lint"
+)
+
+const (
+	flagLintFormat      = "format"
+	flagEnable          = "enable"
+	flagDisable         = "disable"
+	flagMaxParticipants = "max-participants"
+	flagSkin            = "skin"
+	flagListRules       = "list-rules"
+)
+
+type lintOptions struct {
+	Format          string
+	Enable          []string
+	Disable         []string
+	MaxParticipants int
+	Skin            string
+	ListRules       bool
+	ChangedSince    string
+	Staged          bool
+}
+
+const flagUsageLintFormat = `the format in which to report the problems found
+
+supported formats are:
+  ` + diagnostic.FormatText + `   will write one "<file>:<line>: <message> (<rule>)" per line
+  ` + diagnostic.FormatJSON + `   will write a JSON array
+  ` + lint.FormatSARIF + `  will write a SARIF log
+ `
+
+const flagUsageEnable = `the rules to enable, in addition to the rules enabled by default
+ `
+
+const flagUsageDisable = `the rules to disable
+ `
+
+const flagUsageMaxParticipants = `the maximum number of participants in a sequence diagram
+ `
+
+const flagUsageSkin = `the file every diagram should include, used by the ` + lint.RuleSkinInclude + ` rule
+ `
+
+const flagUsageListRules = `list the available rules and exit
+ `
+
+// lintConfigFlags are the flags of lint which are set from the environment or the configuration,
+// the format isn't since it's the format of the report and not of the diagrams.
+var lintConfigFlags = []string{
+	flagEnable, flagDisable, flagMaxParticipants, flagSkin, flagListRules, flagStaged, flagChangedSince,
+}
+
+// CreateLintCmd creates the lint subcommand.
+// Problems in a file can be ignored with a "' gopuml:ignore [rules]" comment on the line before.
+func CreateLintCmd() cobra.Command {
+	opts := lintOptions{
+		Format:          diagnostic.FormatText,
+		MaxParticipants: lint.DefaultMaxParticipants,
+	}
+
+	lintCmd := cobra.Command{
+		Use:   "lint [plant UML files]",
+		Short: "Checks Plant UML files against a set of rules",
+		Long: `Checks Plant UML files against a set of rules.
+
+A problem can be ignored by adding a comment on the line before it:
+  ' gopuml:ignore [rules]
+
+The rules can be set in the lint section of ` + config.Filename + `,
+the enable and disable flags take precedence:
+  lint:
+    rules:
+      hardcoded-color: false
+    maxParticipants: 8`,
+		Example: `  gopuml lint example.puml
+  gopuml lint --disable hardcoded-color --format sarif diagrams/*.puml
+  gopuml lint --changed-since origin/main`,
+		RunE: lintCmdRunFunc(&opts),
+	}
+
+	lintCmd.Flags().StringVar(&opts.Format, flagLintFormat, opts.Format, flagUsageLintFormat)
+	lintCmd.Flags().StringSliceVar(&opts.Enable, flagEnable, opts.Enable, flagUsageEnable)
+	lintCmd.Flags().StringSliceVar(&opts.Disable, flagDisable, opts.Disable, flagUsageDisable)
+	lintCmd.Flags().IntVar(&opts.MaxParticipants, flagMaxParticipants, opts.MaxParticipants, flagUsageMaxParticipants)
+	lintCmd.Flags().StringVar(&opts.Skin, flagSkin, opts.Skin, flagUsageSkin)
+	lintCmd.Flags().BoolVar(&opts.ListRules, flagListRules, opts.ListRules, flagUsageListRules)
+	lintCmd.Flags().StringVar(&opts.ChangedSince, flagChangedSince, opts.ChangedSince, flagUsageChangedSince)
+	lintCmd.Flags().BoolVar(&opts.Staged, flagStaged, opts.Staged, flagUsageStaged)
+	addConfigFlag(&lintCmd)
+
+	return lintCmd
+}
+
+func lintCmdRunFunc(opts *lintOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if opts.ListRules {
+			return listRules(cmd.OutOrStdout())
+		}
+
+		projectCfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		// The format of the configuration is the format of the diagrams, not of the problems.
+		if err = applyDefaults(cmd, projectCfg, lintConfigFlags...); err != nil {
+			return err
+		}
+
+		cfg := opts.config(projectCfg.Lint.Rules)
+
+		rules, err := cfg.EnabledRules()
+		if err != nil {
+			return err
+		}
+
+		if opts.Format != diagnostic.FormatText && opts.Format != diagnostic.FormatJSON && opts.Format != lint.FormatSARIF {
+			return fmt.Errorf("unsupported format: [%s]", opts.Format)
+		}
+
+		files, err := readLintFiles(cmd, args, *opts)
+		if err != nil {
+			return err
+		}
+
+		var diagnostics []diagnostic.Diagnostic
+
+		for _, f := range files {
+			ds, err := lint.Lint(cfg, f)
+			if err != nil {
+				return err
+			}
+
+			diagnostics = append(diagnostics, ds...)
+		}
+
+		if opts.Format == lint.FormatSARIF {
+			err = lint.WriteSARIF(cmd.OutOrStdout(), rules, diagnostics)
+		} else {
+			err = diagnostic.Write(cmd.OutOrStdout(), opts.Format, diagnostics)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(diagnostics) > 0 {
+			// The problems are the report, cobra shouldn't print the error or the usage.
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+
+			return fmt.Errorf("found %d problem(s)", len(diagnostics))
+		}
+
+		return nil
+	}
+}
+
+// config returns the lint config with the rules of the configuration file,
+// which are overridden by the enable and disable flags.
+func (opts lintOptions) config(rules map[string]bool) lint.Config {
+	cfg := lint.DefaultConfig()
+	cfg.MaxParticipants = opts.MaxParticipants
+	cfg.Skin = opts.Skin
+
+	for name, enabled := range rules {
+		cfg.Rules[name] = enabled
+	}
+
+	for _, name := range opts.Enable {
+		cfg.Rules[name] = true
+	}
+
+	for _, name := range opts.Disable {
+		cfg.Rules[name] = false
+	}
+
+	return cfg
+}
+
+func listRules(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0) // nolint: gomnd
+
+	for _, rule := range lint.Rules() {
+		enabled := "disabled"
+		if rule.Enabled {
+			enabled = "enabled"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Name, enabled, rule.Description)
+	}
+
+	return w.Flush()
+}
+
+func readLintFiles(cmd *cobra.Command, args []string, opts lintOptions) ([]lint.File, error) {
+	if len(args) == 0 && opts.ChangedSince == "" {
+		if opts.Staged {
+			return nil, fmt.Errorf("--%s can't be used with stdin", flagStaged)
+		}
+
+		content, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, err
+		}
+
+		return []lint.File{lint.NewFile(stdinFilename, content)}, nil
+	}
+
+	filepaths, err := findAbsolutePaths(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ChangedSince != "" {
+		if filepaths, err = changedFiles(cmd, opts.ChangedSince, filepaths); err != nil {
+			return nil, err
+		}
+	}
+
+	files := make([]lint.File, 0, len(filepaths))
+
+	for _, path := range filepaths {
+		content, err := readFunc(opts.Staged)(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, lint.NewFile(displayPath(path), content))
+	}
+
+	return files, nil
+}
@@ -0,0 +1,120 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+	"github.com/lonnblad/gopuml/example"
+)
+
+func Test_RunLintCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+
+	err := os.WriteFile(inputFile, []byte("@startuml\nBob -> Alice #red : hello\n"), 0600)
+	require.Nil(t, err)
+
+	testcases := []struct {
+		args           []string
+		expectedOutput string
+	}{
+		{
+			args: []string{inputFile},
+			expectedOutput: inputFile + ":1: diagram has no name after @startuml (diagram-name)\n" +
+				inputFile + ":1: @startuml has no matching @enduml (enduml)\n" +
+				inputFile + ":2: hard-coded color: #red (hardcoded-color)\n",
+		},
+		{
+			args:           []string{"--disable", "diagram-name,hardcoded-color", inputFile},
+			expectedOutput: inputFile + ":1: @startuml has no matching @enduml (enduml)\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		cmd := internal.CreateLintCmd()
+		cmd.SetArgs(tc.args)
+
+		var stdout, stderr bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+
+		err = cmd.Execute()
+		assert.NotNil(t, err)
+		assert.Empty(t, stderr.String())
+		assert.Equal(t, tc.expectedOutput, stdout.String())
+	}
+}
+
+func Test_RunLintCommandStdin(t *testing.T) {
+	cmd := internal.CreateLintCmd()
+	cmd.SetArgs([]string{"--format", "json"})
+	cmd.SetIn(bytes.NewBufferString(example.PUML()))
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+	assert.Nil(t, err)
+	assert.Empty(t, stderr.String())
+	assert.Equal(t, "[]\n", stdout.String())
+}
+
+func Test_RunLintCommandWithConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	inputFile := tempDir + "/" + "example.puml"
+
+	require.Nil(t, os.WriteFile(inputFile, []byte("@startuml\nBob -> Alice #red : hello\n@enduml\n"), 0600))
+	require.Nil(t, os.WriteFile(tempDir+"/skin.iuml", []byte("skinparam monochrome true\n"), 0600))
+
+	config := "format: svg\n" +
+		"lint:\n" +
+		"  rules:\n" +
+		"    diagram-name: false\n" +
+		"    hardcoded-color: false\n" +
+		"    skin-include: true\n" +
+		"  skin: skin.iuml\n"
+
+	require.Nil(t, os.WriteFile(tempDir+"/.gopuml.yaml", []byte(config), 0600))
+
+	chdir(t, tempDir)
+
+	// The format of the diagrams isn't the format of the lint report.
+	t.Setenv("GOPUML_FORMAT", "png")
+
+	lint := func(args ...string) string {
+		cmd := internal.CreateLintCmd()
+		cmd.SetArgs(append(args, inputFile))
+
+		var stdout bytes.Buffer
+
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&bytes.Buffer{})
+
+		assert.NotNil(t, cmd.Execute())
+
+		return stdout.String()
+	}
+
+	// The skin is relative to the configuration file, the files are reported relative to the working directory.
+	assert.Equal(t, "example.puml:1: diagram doesn't include the skin: "+tempDir+"/skin.iuml (skin-include)\n", lint())
+
+	assert.Equal(t, "example.puml:1: diagram doesn't include the skin: "+tempDir+"/skin.iuml (skin-include)\n"+
+		"example.puml:2: hard-coded color: #red (hardcoded-color)\n", lint("--enable", "hardcoded-color"))
+
+	require.Nil(t, os.WriteFile(tempDir+"/.gopuml.yaml", []byte("lint:\n  rules:\n    unknown: true\n"), 0600))
+
+	cmd := internal.CreateLintCmd()
+	cmd.SetArgs([]string{inputFile})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	assert.EqualError(t, cmd.Execute(), "unknown lint rule: [unknown]")
+}
@@ -0,0 +1,366 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/docs"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+	"github.com/lonnblad/gopuml/internal/render"
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+const (
+	flagOutDir = "out-dir"
+	flagCheck  = "check"
+)
+
+type markdownOptions struct {
+	Server string
+	Style  string
+	Format string
+	OutDir string
+	Check  bool
+
+	Client clientOptions
+}
+
+const flagUsageMarkdownStyle = `the style in which to reference the rendered diagrams
+
+supported styles are:
+  ` + styleFile + `  will write the formatted content to an image file and reference it
+  ` + styleLink + `  will reference a link to the formatted content
+ `
+
+const flagUsageMarkdownFormat = `the format of the rendered diagrams
+
+supported formatters are:
+  ` + formatPNG + `  will format the content as .png
+  ` + formatSVG + `  will format the content as .svg
+ `
+
+const flagUsageOutDir = `the directory to write the image files to, relative to the Markdown file
+ `
+
+const flagUsageCheck = `check that the Markdown files and images are up to date without writing them,
+fails if any file would change
+ `
+
+// markdownConfigFlags are the flags of markdown which are set from the environment or the configuration.
+var markdownConfigFlags = append([]string{flagStyle, flagFormat, flagServer, flagOutDir, flagCheck}, clientFlags...)
+
+// CreateMarkdownCmd creates the markdown subcommand.
+// The command renders the plantuml and puml code fences in Markdown files
+// and inserts a reference to the rendered diagram after every fence.
+func CreateMarkdownCmd() cobra.Command {
+	opts := markdownOptions{
+		Server: defaultServer,
+		Style:  defaultStyle,
+		Format: defaultFormat,
+	}
+
+	markdownCmd := cobra.Command{
+		Use:   "markdown [Markdown files or directories]",
+		Short: "Renders Plant UML code fences in Markdown files",
+		Long: `Renders the plantuml and puml code fences in Markdown files.
+
+A reference to the rendered diagram is inserted after every fence, between the comments:
+  ` + docs.ReferenceBegin + `
+  ` + docs.ReferenceEnd + `
+The reference is updated when the command is run again.`,
+		Example: `  gopuml markdown README.md
+  gopuml markdown --out-dir img docs
+  gopuml markdown --check docs`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: markdownCmdRunFunc(&opts),
+	}
+
+	markdownCmd.Flags().StringVarP(&opts.Format, flagFormat, flagShortFormat, opts.Format, flagUsageMarkdownFormat)
+	markdownCmd.Flags().StringVar(&opts.Server, flagServer, opts.Server, flagUsageServer)
+	markdownCmd.Flags().StringVar(&opts.Style, flagStyle, opts.Style, flagUsageMarkdownStyle)
+	markdownCmd.Flags().StringVar(&opts.OutDir, flagOutDir, opts.OutDir, flagUsageOutDir)
+	markdownCmd.Flags().BoolVar(&opts.Check, flagCheck, opts.Check, flagUsageCheck)
+	addClientFlags(markdownCmd.Flags(), &opts.Client)
+	addConfigFlag(&markdownCmd)
+
+	return markdownCmd
+}
+
+func markdownCmdRunFunc(opts *markdownOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err = applyDefaults(cmd, cfg, markdownConfigFlags...); err != nil {
+			return err
+		}
+
+		if opts.Style != styleFile && opts.Style != styleLink {
+			return fmt.Errorf("unsupported style: [%s]", opts.Style)
+		}
+
+		if opts.Format != formatPNG && opts.Format != formatSVG {
+			return fmt.Errorf("unsupported format: [%s]", opts.Format)
+		}
+
+		filepaths, err := findDocuments(args, markdownExtensions)
+		if err != nil {
+			return err
+		}
+
+		renderer, err := newRenderer(opts.Server, opts.Client)
+		if err != nil {
+			return err
+		}
+
+		m := markdownRenderer{opts: *opts, cmd: cmd, renderer: renderer}
+
+		for _, path := range filepaths {
+			if err = m.renderFile(path); err != nil {
+				return interrupted(cmd, err)
+			}
+		}
+
+		return m.report()
+	}
+}
+
+// markdownRenderer renders the fences in Markdown files and collects
+// the diagnostics and the files which are out of date.
+type markdownRenderer struct {
+	opts     markdownOptions
+	cmd      *cobra.Command
+	renderer *render.Renderer
+
+	diagnostics []diagnostic.Diagnostic
+	outOfDate   []string
+}
+
+func (m *markdownRenderer) renderFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := docs.SplitLines(string(content))
+	fences := docs.FindFences(lines)
+
+	if len(fences) == 0 {
+		return nil
+	}
+
+	references := make([]string, len(fences))
+	usedNames := make(map[string]int)
+
+	for idx, fence := range fences {
+		name := source.DiagramName([]byte(fence.Content))
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), idx+1)
+		}
+
+		if references[idx], err = m.renderFence(path, fence, name, usedNames); err != nil {
+			return err
+		}
+	}
+
+	updatedLines, err := docs.InsertReferences(lines, fences, references)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayPath(path), err)
+	}
+
+	updated := []byte(strings.Join(updatedLines, lineEnding(content)))
+
+	return m.writeFile(path, content, updated)
+}
+
+// renderFence renders the fence and returns the reference to the rendered diagram.
+func (m *markdownRenderer) renderFence(path string, fence docs.Fence, name string, usedNames map[string]int) (string, error) {
+	encoded, err := compressAndEncode([]byte(fence.Content))
+	if err != nil {
+		return "", err
+	}
+
+	if m.opts.Style == styleLink {
+		link := m.renderer.Link(m.opts.Format, encoded)
+
+		if warning := render.LinkWarning(link); warning != "" {
+			d := diagnostic.Diagnostic{File: displayPath(path), Line: fence.Start + 1, Message: "warning: " + warning}
+			m.cmd.PrintErrln(d.String())
+		}
+
+		return fmt.Sprintf("![%s](%s)", name, link), nil
+	}
+
+	filename := slug(name)
+	if filename == "" {
+		filename = "diagram"
+	}
+
+	if usedNames[filename]++; usedNames[filename] > 1 {
+		filename = fmt.Sprintf("%s-%d", filename, usedNames[filename])
+	}
+
+	imagePath := filepath.Join(filepath.Dir(path), m.opts.OutDir, filename+"."+m.opts.Format)
+	reference := fmt.Sprintf("![%s](%s)", name, relativeLink(filepath.Dir(path), imagePath))
+
+	var image bytes.Buffer
+
+	err = m.renderer.Render(m.cmd.Context(), &image, m.opts.Format, encoded)
+	if diagramErr, ok := render.AsDiagramError(err); ok {
+		m.diagnostics = append(m.diagnostics, diagnostic.Diagnostic{
+			File:    displayPath(path),
+			Line:    fence.Start + 1 + diagramErr.Line,
+			Message: diagramErr.Message,
+		})
+
+		return reference, nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if err = m.cmd.Context().Err(); err != nil {
+		return "", err
+	}
+
+	existing, err := os.ReadFile(imagePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	return reference, m.writeFile(imagePath, existing, image.Bytes())
+}
+
+// writeFile writes the updated content if it differs from the current content,
+// in check mode, the file is reported as out of date instead.
+func (m *markdownRenderer) writeFile(path string, current, updated []byte) error {
+	if bytes.Equal(current, updated) {
+		return nil
+	}
+
+	if m.opts.Check {
+		m.outOfDate = append(m.outOfDate, displayPath(path))
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil { // nolint: gomnd
+		return fmt.Errorf("couldn't create directory: %w", err)
+	}
+
+	const readWriteMode = 0600
+	if _, err := fsutil.WriteFile(path, updated, readWriteMode); err != nil {
+		return fmt.Errorf("couldn't write file: %w", err)
+	}
+
+	fmt.Fprintln(m.cmd.OutOrStdout(), "updated file:", displayPath(path))
+
+	return nil
+}
+
+func (m *markdownRenderer) report() error {
+	if len(m.diagnostics) > 0 {
+		if err := diagnostic.Write(m.cmd.ErrOrStderr(), diagnostic.FormatText, m.diagnostics); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range m.outOfDate {
+		m.cmd.PrintErrln(path + ": out of date")
+	}
+
+	if len(m.diagnostics) == 0 && len(m.outOfDate) == 0 {
+		return nil
+	}
+
+	m.cmd.SilenceErrors = true
+	m.cmd.SilenceUsage = true
+
+	return fmt.Errorf("found %d diagram error(s) and %d file(s) out of date", len(m.diagnostics), len(m.outOfDate))
+}
+
+var markdownExtensions = map[string]bool{".md": true, ".markdown": true}
+
+// findDocuments returns the absolute paths of the files, directories are walked
+// to find the files with any of the extensions.
+func findDocuments(args []string, extensions map[string]bool) ([]string, error) {
+	var paths []string
+
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+
+		err = filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && path != arg && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			if !entry.IsDir() && extensions[strings.ToLower(filepath.Ext(path))] {
+				paths = append(paths, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't walk directory: [%s]: %w", arg, err)
+		}
+	}
+
+	return findAbsolutePaths(paths)
+}
+
+// lineEnding returns the line ending used in the content.
+func lineEnding(content []byte) string {
+	if bytes.Contains(content, []byte("\r\n")) {
+		return "\r\n"
+	}
+
+	return "\n"
+}
+
+// relativeLink returns a link to the path relative to the directory.
+func relativeLink(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+
+	return filepath.ToSlash(path)
+}
+
+// slug converts the name into a lower case filename.
+func slug(name string) string {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return '-'
+	}, name)
+
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "-")
+	}
+
+	return strings.Trim(s, "-")
+}
@@ -0,0 +1,74 @@
+package internal_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+const markdownDoc = "# Example\n\n```plantuml\n@startuml Example\nBob -> Alice : hello\n@enduml\n```\n\nThe end.\n"
+
+func Test_RunMarkdownCommand(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	docFile := tempDir + "/" + "doc.md"
+
+	err := os.WriteFile(docFile, []byte(markdownDoc), 0600)
+	require.Nil(t, err)
+
+	stdout, _, err := executeMarkdownCmd("--server", server.URL, "--out-dir", "img", docFile)
+	require.Nil(t, err)
+	assert.Equal(t, "updated file: "+tempDir+"/img/example.svg\nupdated file: "+docFile+"\n", stdout)
+
+	actualDoc, err := os.ReadFile(docFile)
+	require.Nil(t, err)
+
+	expectedDoc := strings.Replace(markdownDoc, "```\n\n", "```\n<!-- gopuml:begin -->\n![Example](img/example.svg)\n<!-- gopuml:end -->\n\n", 1)
+	assert.Equal(t, expectedDoc, string(actualDoc))
+
+	image, err := os.ReadFile(tempDir + "/img/example.svg")
+	require.Nil(t, err)
+	assert.Equal(t, string(plantumltest.Render("svg", []byte(server.Requests()[0].Source))), string(image))
+
+	stdout, _, err = executeMarkdownCmd("--server", server.URL, "--out-dir", "img", "--check", tempDir)
+	require.Nil(t, err)
+	assert.Empty(t, stdout)
+
+	err = os.WriteFile(docFile, []byte(strings.Replace(string(actualDoc), "hello", "hi", 1)), 0600)
+	require.Nil(t, err)
+
+	_, stderr, err := executeMarkdownCmd("--server", server.URL, "--out-dir", "img", "--check", tempDir)
+	assert.NotNil(t, err)
+	assert.Equal(t, tempDir+"/img/example.svg: out of date\n", stderr)
+
+	_, _, err = executeMarkdownCmd("--server", server.URL, "--style", "link", docFile)
+	require.Nil(t, err)
+
+	actualDoc, err = os.ReadFile(docFile)
+	require.Nil(t, err)
+	assert.Contains(t, string(actualDoc), "<!-- gopuml:begin -->\n![Example]("+server.URL+"/svg/")
+	assert.Equal(t, 1, strings.Count(string(actualDoc), "<!-- gopuml:begin -->"))
+}
+
+func executeMarkdownCmd(args ...string) (string, string, error) {
+	cmd := internal.CreateMarkdownCmd()
+	cmd.SetArgs(args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
@@ -1,9 +1,14 @@
 package internal
 
 import (
+	"errors"
+
 	"github.com/spf13/cobra"
 )
 
+// errInterrupted is returned by commands which are interrupted, for example by Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
 // CreateRootCmd creates the root command.
 func CreateRootCmd() cobra.Command {
 	rootCmd := cobra.Command{
@@ -13,3 +18,15 @@ func CreateRootCmd() cobra.Command {
 
 	return rootCmd
 }
+
+// interrupted returns errInterrupted instead of the error when the context of the command is done,
+// the usage isn't printed since it's not the cause of the error.
+func interrupted(cmd *cobra.Command, err error) error {
+	if err == nil || cmd.Context() == nil || cmd.Context().Err() == nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+
+	return errInterrupted
+}
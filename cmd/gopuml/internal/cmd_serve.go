@@ -2,31 +2,58 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"mime"
+	"net"
 	"net/http"
 	"os"
-	"text/template"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 
+	"github.com/lonnblad/gopuml/internal/config"
 	"github.com/lonnblad/gopuml/internal/generator"
+	"github.com/lonnblad/gopuml/internal/render"
 )
 
 const (
 	defaultPort = "8080"
+	defaultSort = string(generator.OrderPath)
 
 	flagPort, flagShortPort = "port", "p"
+	flagSort                = "sort"
 )
 
 type serveOptions struct {
-	Port string
+	Port   string
+	Sort   string
+	Server string
+
+	Client clientOptions
 }
 
 const flagUsagePort = `the port to use to serve the HTML page
  `
 
+const flagUsageSort = `the order in which to list the files on the HTML page,
+files are grouped by directory
+
+supported orders are:
+  ` + string(generator.OrderPath) + `      will sort the files by path
+  ` + string(generator.OrderName) + `      will sort the files by filename
+  ` + string(generator.OrderModified) + `  will sort the files with the latest modified first
+ `
+
+const flagUsageServeServer = `the Server URL to use to render the diagrams
+ `
+
+// serveConfigFlags are the flags of serve which are set from the environment or the configuration.
+var serveConfigFlags = append([]string{flagPort, flagSort, flagServer}, clientFlags...)
+
 // CreateServeCmd creates the serve subcommand.
 // The command will run a webserver which renders the supplied Plant UML files as a static HTML page.
 // The command uses a file watcher to keep track of any modifications to the supplied files.
@@ -34,40 +61,86 @@ const flagUsagePort = `the port to use to serve the HTML page
 // When modifications are found, the server will answer the HEAD request with a 200 OK.
 func CreateServeCmd() cobra.Command {
 	opts := serveOptions{
-		Port: defaultPort,
+		Port:   defaultPort,
+		Sort:   defaultSort,
+		Server: defaultServer,
 	}
 
 	serveCmd := cobra.Command{
 		Use:   "serve",
 		Short: "Starts a web server which serves compiled UML files on a static HTML page.",
 		Long: `Starts a web server which serves compiled UML files.
-On modifications to the files, the HTML page will reload.`,
+On modifications to the files, the HTML page will reload.
+
+The defaults for the flags are read from environment variables, like GOPUML_PORT,
+and from the configuration file, ` + config.Filename + `, in that order.`,
 		RunE: serveCmdRunFunc(&opts),
 	}
 
 	serveCmd.Flags().StringVarP(&opts.Port, flagPort, flagShortPort, opts.Port, flagUsagePort)
+	serveCmd.Flags().StringVar(&opts.Sort, flagSort, opts.Sort, flagUsageSort)
+	serveCmd.Flags().StringVar(&opts.Server, flagServer, opts.Server, flagUsageServeServer)
+	addClientFlags(serveCmd.Flags(), &opts.Client)
+	addConfigFlag(&serveCmd)
 
 	return serveCmd
 }
 
 func serveCmdRunFunc(opts *serveOptions) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err = applyDefaults(cmd, cfg, serveConfigFlags...); err != nil {
+			return err
+		}
+
+		order, err := generator.ParseOrder(opts.Sort)
+		if err != nil {
+			return err
+		}
+
+		baseDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		renderer, err := newRenderer(opts.Server, opts.Client)
+		if err != nil {
+			return err
+		}
+
 		generator := generator.New()
+		generator.SetValidator(renderer.Validate)
 
 		fileWatcher, err := fsnotify.NewWatcher()
 		if err != nil {
 			return err
 		}
 
-		defer fileWatcher.Close()
+		ctx := cmd.Context()
+		handlerDone := make(chan struct{})
 
-		go eventHandler(cmd, fileWatcher, generator)
+		go func() {
+			defer close(handlerDone)
+			eventHandler(ctx, cmd, fileWatcher, generator)
+		}()
 
-		if err = readAllFiles(fileWatcher, generator, args); err != nil {
-			return err
+		// Closing the watcher stops the event handler, which is waited for.
+		defer func() {
+			fileWatcher.Close()
+			<-handlerDone
+		}()
+
+		if err = readAllFiles(ctx, cmd, fileWatcher, generator, args); err != nil {
+			return interrupted(cmd, err)
 		}
 
-		if err = runServer(cmd, opts.Port, generator); err != nil {
+		page := pageOptions{Order: order, BaseDir: baseDir, Renderer: renderer}
+
+		if err = runServer(ctx, cmd, opts.Port, page, generator); err != nil {
 			return err
 		}
 
@@ -75,30 +148,52 @@ func serveCmdRunFunc(opts *serveOptions) func(cmd *cobra.Command, args []string)
 	}
 }
 
-func eventHandler(cmd *cobra.Command, watcher *fsnotify.Watcher, gen *generator.Generator) {
+func eventHandler(ctx context.Context, cmd *cobra.Command, watcher *fsnotify.Watcher, gen *generator.Generator) {
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
 			}
 
-			if event.Op&fsnotify.Write == fsnotify.Write {
-				path := event.Name
+			path := event.Name
 
-				fmt.Fprintln(cmd.OutOrStdout(), "modified file:", path)
+			switch {
+			case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+				// Editors which save by renaming or removing the file end the watch,
+				// it's added again for the file which replaces it.
+				if err := rewatch(ctx, watcher, path); err != nil {
+					if ctx.Err() == nil {
+						cmd.PrintErrln(err)
+					}
 
-				content, err := os.ReadFile(path)
-				if err != nil {
-					cmd.PrintErrln(err)
-					return
+					continue
 				}
+			case event.Op&fsnotify.Write == 0:
+				continue
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "modified file:", path)
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				cmd.PrintErrln(err)
+				continue
+			}
 
-				if err = gen.PutFile(path, content); err != nil {
-					cmd.PrintErrln(err)
+			if err = gen.PutFile(ctx, path, content); err != nil {
+				if ctx.Err() != nil {
 					return
 				}
+
+				cmd.PrintErrln(err)
+
+				continue
 			}
+
+			printFileError(cmd, gen, path)
 		case err, ok := <-watcher.Errors:
 			if !ok {
 				return
@@ -109,7 +204,30 @@ func eventHandler(cmd *cobra.Command, watcher *fsnotify.Watcher, gen *generator.
 	}
 }
 
-func readAllFiles(fileWatcher *fsnotify.Watcher, gen *generator.Generator, args []string) error {
+// rewatchAttempts and rewatchInterval limit how long to wait for a removed file to be replaced.
+const (
+	rewatchAttempts = 20
+	rewatchInterval = 50 * time.Millisecond
+)
+
+// rewatch adds the watch of the path again, it waits for the file to be replaced if it doesn't exist.
+func rewatch(ctx context.Context, watcher *fsnotify.Watcher, path string) (err error) {
+	for attempt := 0; attempt < rewatchAttempts; attempt++ {
+		if err = watcher.Add(path); err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rewatchInterval):
+		}
+	}
+
+	return fmt.Errorf("couldn't watch the file, it was removed: %w", err)
+}
+
+func readAllFiles(ctx context.Context, cmd *cobra.Command, fileWatcher *fsnotify.Watcher, gen *generator.Generator, args []string) error {
 	filepaths, err := findAbsolutePaths(args)
 	if err != nil {
 		return err
@@ -125,26 +243,57 @@ func readAllFiles(fileWatcher *fsnotify.Watcher, gen *generator.Generator, args
 			return err
 		}
 
-		if err = gen.PutFile(path, content); err != nil {
+		if err = gen.PutFile(ctx, path, content); err != nil {
 			return err
 		}
+
+		printFileError(cmd, gen, path)
 	}
 
 	return nil
 }
 
-func runServer(cmd *cobra.Command, port string, gen *generator.Generator) error {
+func printFileError(cmd *cobra.Command, gen *generator.Generator, path string) {
+	if file, ok := gen.GetFile(path); ok && file.Err != nil {
+		cmd.PrintErrf("%s: %s\n", path, file.Err)
+	}
+}
+
+// shutdownGracePeriod is the time to wait for requests to finish when the server is shut down.
+const shutdownGracePeriod = 5 * time.Second
+
+// runServer serves the pages until the context is done, then the server is shut down gracefully.
+func runServer(ctx context.Context, cmd *cobra.Command, port string, page pageOptions, gen *generator.Generator) error {
 	server := &http.Server{
 		Addr:              ":" + port,
-		Handler:           handler(gen),
+		Handler:           handler(page, gen),
 		ReadHeaderTimeout: 10 * time.Second, // nolint: gomnd
+		// The requests are cancelled with the context, which ends the long-polling requests on shut down.
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	fmt.Fprintln(cmd.OutOrStdout(), "Server started")
 	fmt.Fprintf(cmd.OutOrStdout(), "  http://localhost:%s\n\n", port)
 
-	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+	serverErr := make(chan error, 1)
+
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
 		return err
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "Shutting down the server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("couldn't shut down the server: %w", err)
 	}
 
 	return nil
@@ -153,137 +302,170 @@ func runServer(cmd *cobra.Command, port string, gen *generator.Generator) error
 const (
 	contentType = "Content-Type"
 	mimeHTML    = "text/html"
+
+	diagramPathPrefix = "/d/"
 )
 
-func handler(gen *generator.Generator) http.Handler {
+var downloadMimeTypes = map[string]string{
+	formatPNG: "image/png",
+	formatSVG: "image/svg+xml",
+	formatTXT: "text/plain; charset=utf-8",
+}
+
+// pageOptions holds the options for how to render the HTML pages.
+type pageOptions struct {
+	Order    generator.Order
+	BaseDir  string
+	Renderer *render.Renderer
+}
+
+// handler serves an index page listing all files on "/",
+// a page per file on "/d/<path>" and the rendered file on "/d/<path>?download=<format>".
+func handler(page pageOptions, gen *generator.Generator) http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
-		if req.Method == "HEAD" {
-			handleHEAD(gen, w, req)
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
 			return
 		}
 
-		w.Header().Set(contentType, mimeHTML)
+		if req.Method == http.MethodHead {
+			handleHEAD(gen, func(generator.File) bool { return true }, w, req)
+			return
+		}
 
-		content, err := buildHTML(gen.GetFiles())
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("")) // nolint: errcheck
+		content, err := buildIndexHTML(page, gen.GetSortedFiles(page.Order))
+		writeHTML(w, content, err)
+	})
 
+	mux.HandleFunc(diagramPathPrefix, func(w http.ResponseWriter, req *http.Request) {
+		file, ok := findFile(page, gen, strings.TrimPrefix(req.URL.Path, diagramPathPrefix))
+		if !ok {
+			http.NotFound(w, req)
 			return
 		}
 
-		w.Write(content) // nolint: errcheck
+		if req.Method == http.MethodHead {
+			handleHEAD(gen, func(f generator.File) bool { return f.Filepath == file.Filepath }, w, req)
+			return
+		}
+
+		if download := req.URL.Query().Get("download"); download != "" {
+			if !supportedFormat(download) {
+				http.Error(w, fmt.Sprintf("unsupported format: [%s]", download), http.StatusBadRequest)
+				return
+			}
+
+			writeDownload(w, req, page, file, download)
+
+			return
+		}
+
+		format := req.URL.Query().Get("format")
+		if format == "" {
+			format = formatSVG
+		}
+
+		if !supportedFormat(format) {
+			http.Error(w, fmt.Sprintf("unsupported format: [%s]", format), http.StatusBadRequest)
+			return
+		}
+
+		content, err := buildDiagramHTML(page, file, format)
+		writeHTML(w, content, err)
 	})
 
 	return mux
 }
 
-func handleHEAD(gen *generator.Generator, w http.ResponseWriter, req *http.Request) {
-	content := []byte("")
+func writeHTML(w http.ResponseWriter, content []byte, err error) {
+	w.Header().Set(contentType, mimeHTML)
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("")) // nolint: errcheck
 
-	etag := req.Header.Get("If-Modified-Since")
-	if etag == "" {
-		w.Write(content) // nolint: errcheck
 		return
 	}
 
-	since, err := time.Parse(time.RFC1123, etag)
-	if err != nil {
-		w.Write(content) // nolint: errcheck
+	w.Write(content) // nolint: errcheck
+}
+
+func supportedFormat(format string) bool {
+	return format == formatPNG || format == formatSVG || format == formatTXT
+}
+
+// writeDownload renders the file in the format and serves it as an attachment,
+// since browsers ignore the download attribute of links to the Plant UML server.
+func writeDownload(w http.ResponseWriter, req *http.Request, page pageOptions, file generator.File, format string) {
+	var content bytes.Buffer
+
+	if err := page.Renderer.Render(req.Context(), &content, format, renderedEncoding(file)); err != nil {
+		http.Error(w, fmt.Sprintf("couldn't render the diagram: %v", err), http.StatusBadGateway)
 		return
 	}
 
-	id, contentChan := gen.RegisterSub()
-	defer gen.DeregisterSub(id)
+	filename := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + "." + format
+
+	w.Header().Set(contentType, downloadMimeTypes[format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.Write(content.Bytes()) // nolint: errcheck
+}
 
+// findFile finds the file which is served on the given diagram path.
+func findFile(page pageOptions, gen *generator.Generator, path string) (generator.File, bool) {
 	for _, file := range gen.GetFiles() {
-		if file.UpdatedAt.After(since) {
-			w.Write(content) // nolint: errcheck
-			return
+		if diagramPath(page.BaseDir, file.Filepath) == path {
+			return file, true
 		}
 	}
 
-	const longPollingTimeout = 60 * time.Second
+	return generator.File{}, false
+}
 
-	select {
-	case <-contentChan:
-	case <-time.After(longPollingTimeout):
-		w.WriteHeader(http.StatusNotModified)
-	}
+// handleHEAD will wait for any of the files matching the filter to be updated
+// after the time in the If-Modified-Since header.
+func handleHEAD(gen *generator.Generator, filter func(generator.File) bool, w http.ResponseWriter, req *http.Request) {
+	content := []byte("")
 
-	w.Write(content) // nolint: errcheck
-}
+	etag := req.Header.Get("If-Modified-Since")
+	if etag == "" {
+		w.Write(content) // nolint: errcheck
+		return
+	}
 
-func buildHTML(files []generator.File) (_ []byte, err error) {
-	generator, err := template.New("html_page").Parse(htmlPageTemplate)
+	since, err := time.Parse(time.RFC1123, etag)
 	if err != nil {
-		err = fmt.Errorf("failed to parse HTML page template: %w", err)
+		w.Write(content) // nolint: errcheck
 		return
 	}
 
-	type file struct {
-		Filename string
-		PngLink  string
-		SvgLink  string
-	}
+	id, contentChan := gen.RegisterSub()
+	defer gen.DeregisterSub(id)
 
-	var templateInfo = struct{ Files []file }{Files: make([]file, len(files))}
+	const longPollingTimeout = 60 * time.Second
 
-	for idx, f := range files {
-		templateInfo.Files[idx].Filename = f.Filename
-		templateInfo.Files[idx].PngLink = createLink(defaultServer, formatPNG, f.Encoded)
-		templateInfo.Files[idx].SvgLink = createLink(defaultServer, formatSVG, f.Encoded)
-	}
+	timeout := time.After(longPollingTimeout)
 
-	var buffer bytes.Buffer
-	if err = generator.Execute(&buffer, templateInfo); err != nil {
-		err = fmt.Errorf("failed to execute generator: %w", err)
-		return
-	}
+	for {
+		for _, file := range gen.GetFiles() {
+			if filter(file) && file.UpdatedAt.After(since) {
+				w.Write(content) // nolint: errcheck
+				return
+			}
+		}
 
-	return buffer.Bytes(), nil
-}
+		select {
+		case <-contentChan:
+		case <-req.Context().Done():
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case <-timeout:
+			w.WriteHeader(http.StatusNotModified)
+			w.Write(content) // nolint: errcheck
 
-const htmlPageTemplate = `<!DOCTYPE html>
-<html lang=en>
-<head>
-  <title>gopuml</title>
-  <meta name='generator' content='github.com/lonnblad/gopuml'>
-</head>
-<body onload="javascript:checkReload();" style="width:100vw;height:100vh;background-color:lightgrey;">
-  <div style="margin: 0px 20px;width:100%">
-    {{range .Files}}
-    <h2>{{.Filename}}</h2>
-
-    <h3>.png</h3>
-		Static <a href="{{.PngLink}}">.png link</a> from plantuml.com.
-    <p>
-      <img style="object-fit:contain;" src="{{.PngLink}}" alt=".png" />
-    </p>
-
-    <h3>.svg</h3>
-		Static <a href="{{.SvgLink}}">.svg link</a> from plantuml.com.
-    <p>
-      <img style="object-fit:contain;" src="{{.SvgLink}}" alt=".svg" />
-    </p>
-    {{end}}
-  </div>
-  <script>
-    function checkReload() {
-			const timestamp = new Date().toUTCString();
-
-      fetch('/', {method: 'HEAD', headers: {'If-Modified-Since': timestamp}})
-        .then(response => {
-					if (response.status === 200) {
-						location.reload(true)
-					} else {
-						checkReload()
-					}
-				})
-				.catch(err => console.log(err));
-    }
-  </script>
-</body>
-</html>`
+			return
+		}
+	}
+}
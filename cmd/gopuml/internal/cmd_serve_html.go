@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/lonnblad/gopuml/internal/generator"
+	"github.com/lonnblad/gopuml/internal/render"
+)
+
+type htmlLink struct {
+	Format string
+	Link   string
+}
+
+func buildIndexHTML(page pageOptions, files []generator.File) (_ []byte, err error) {
+	type file struct {
+		ID       string
+		Filename string
+		PageLink string
+		SvgLink  string
+		Error    string
+	}
+
+	type group struct {
+		Dir   string
+		Files []file
+	}
+
+	var templateInfo = struct{ Groups []*group }{}
+
+	groupsByDir := make(map[string]*group)
+
+	for _, f := range files {
+		dir := displayDir(page.BaseDir, f.Filepath)
+
+		g, ok := groupsByDir[dir]
+		if !ok {
+			g = &group{Dir: dir}
+			groupsByDir[dir] = g
+			templateInfo.Groups = append(templateInfo.Groups, g)
+		}
+
+		g.Files = append(g.Files, file{
+			ID:       anchorID(page.BaseDir, f.Filepath),
+			Filename: f.Filename,
+			PageLink: diagramPathPrefix + diagramPath(page.BaseDir, f.Filepath),
+			SvgLink:  page.Renderer.Link(formatSVG, renderedEncoding(f)),
+			Error:    newHTMLError(f.Err).Message,
+		})
+	}
+
+	return executeTemplate(indexPageTemplate, templateInfo)
+}
+
+func buildDiagramHTML(page pageOptions, f generator.File, format string) (_ []byte, err error) {
+	var templateInfo = struct {
+		Filename    string
+		Dir         string
+		PagePath    string
+		Format      string
+		Link        string
+		Formats     []htmlLink
+		Downloads   []htmlLink
+		Basename    string
+		Source      []htmlSourceLine
+		IsTextView  bool
+		Error       htmlError
+		IsLastValid bool
+	}{
+		Filename:    f.Filename,
+		Dir:         displayDir(page.BaseDir, f.Filepath),
+		PagePath:    diagramPathPrefix + diagramPath(page.BaseDir, f.Filepath),
+		Format:      format,
+		Link:        page.Renderer.Link(format, renderedEncoding(f)),
+		Basename:    strings.TrimSuffix(f.Filename, filepath.Ext(f.Filename)),
+		IsTextView:  format == formatTXT,
+		Error:       newHTMLError(f.Err),
+		IsLastValid: f.Err != nil && len(f.LastValidEncoded) > 0,
+	}
+
+	for idx, line := range strings.Split(strings.TrimRight(string(f.Raw), "\n"), "\n") {
+		lineNumber := idx + 1
+
+		templateInfo.Source = append(templateInfo.Source, htmlSourceLine{
+			ID:      fmt.Sprintf("L%d", lineNumber),
+			Text:    line,
+			IsError: lineNumber == templateInfo.Error.Line,
+		})
+	}
+
+	for _, format := range []string{formatSVG, formatPNG, formatTXT} {
+		templateInfo.Formats = append(templateInfo.Formats, htmlLink{Format: format, Link: "?format=" + format})
+		templateInfo.Downloads = append(templateInfo.Downloads, htmlLink{Format: format, Link: "?download=" + format})
+	}
+
+	return executeTemplate(diagramPageTemplate, templateInfo)
+}
+
+type htmlSourceLine struct {
+	ID      string
+	Text    string
+	IsError bool
+}
+
+type htmlError struct {
+	Message string
+	Line    int
+}
+
+func newHTMLError(err error) htmlError {
+	if err == nil {
+		return htmlError{}
+	}
+
+	if diagramErr, ok := render.AsDiagramError(err); ok {
+		return htmlError{Message: diagramErr.Message, Line: diagramErr.Line}
+	}
+
+	return htmlError{Message: err.Error()}
+}
+
+// renderedEncoding returns the encoding of the file to render,
+// which is the last valid version of the file when the current version has an error.
+func renderedEncoding(f generator.File) []byte {
+	if f.Err != nil && len(f.LastValidEncoded) > 0 {
+		return f.LastValidEncoded
+	}
+
+	return f.Encoded
+}
+
+func executeTemplate(text string, data interface{}) (_ []byte, err error) {
+	generator, err := template.New("html_page").Parse(htmlBaseTemplate)
+	if err != nil {
+		err = fmt.Errorf("failed to parse HTML page template: %w", err)
+		return
+	}
+
+	if _, err = generator.Parse(text); err != nil {
+		err = fmt.Errorf("failed to parse HTML page template: %w", err)
+		return
+	}
+
+	var buffer bytes.Buffer
+	if err = generator.Execute(&buffer, data); err != nil {
+		err = fmt.Errorf("failed to execute generator: %w", err)
+		return
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// diagramPath returns the path on which the page for the file is served,
+// relative to the base directory when possible.
+func diagramPath(baseDir, path string) string {
+	if rel, err := filepath.Rel(baseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+
+	return strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
+
+// displayDir returns the directory of the path, relative to the base directory when possible.
+func displayDir(baseDir, path string) string {
+	return filepath.ToSlash(filepath.Dir(diagramPath(baseDir, path)))
+}
+
+// anchorID returns an HTML id for the path, which is stable between reloads.
+func anchorID(baseDir, path string) string {
+	return "diagram-" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+
+		return '-'
+	}, diagramPath(baseDir, path))
+}
+
+const htmlBaseTemplate = `<!DOCTYPE html>
+<html lang=en>
+<head>
+  <title>{{block "title" .}}gopuml{{end}}</title>
+  <meta name='generator' content='github.com/lonnblad/gopuml'>
+  <style>
+    body { display:flex; margin:0px; width:100vw; height:100vh; background-color:lightgrey; font-family:sans-serif; }
+    nav { flex:none; width:250px; height:100vh; overflow-y:auto; padding:0px 20px; background-color:white; }
+    nav ul { list-style:none; padding-left:10px; }
+    main { flex:auto; height:100vh; overflow-y:auto; padding:0px 20px; }
+    .cards { display:flex; flex-wrap:wrap; gap:20px; }
+    .card { width:300px; padding:10px; background-color:white; text-decoration:none; color:black; }
+    .card img { width:100%; height:200px; object-fit:contain; }
+    .buttons a { display:inline-block; margin-right:10px; padding:5px 10px; background-color:white; color:black; }
+    .buttons a.selected { background-color:black; color:white; }
+    .diagram { margin:20px 0px; }
+    .diagram img { max-width:100%; object-fit:contain; }
+    .diagram iframe { width:100%; height:60vh; border:none; background-color:white; }
+    pre.source { padding:10px; background-color:white; counter-reset:line; }
+    pre.source span { display:block; }
+    pre.source span.error { background-color:#ffd6d6; }
+    .error-overlay { position:sticky; top:0px; z-index:1; padding:10px 20px; background-color:#b00020; color:white; }
+    .error-overlay a { color:white; }
+    .card .error { color:#b00020; }
+    pre.source span:before { counter-increment:line; content:counter(line); display:inline-block; width:3em; color:grey; }
+  </style>
+</head>
+<body onload="javascript:checkReload();">
+  {{block "body" .}}{{end}}
+  <script>
+    function checkReload() {
+      const timestamp = new Date().toUTCString();
+
+      fetch({{block "reload" .}}'/'{{end}}, {method: 'HEAD', headers: {'If-Modified-Since': timestamp}})
+        .then(response => {
+          if (response.status === 200) {
+            location.reload(true)
+          } else {
+            checkReload()
+          }
+        })
+        .catch(err => console.log(err));
+    }
+  </script>
+</body>
+</html>`
+
+const indexPageTemplate = `{{define "body"}}
+  <nav>
+    <h2>Contents</h2>
+    {{range .Groups}}
+    <h4>{{.Dir}}</h4>
+    <ul>
+      {{range .Files}}
+      <li><a href="#{{.ID}}">{{.Filename}}</a></li>
+      {{end}}
+    </ul>
+    {{end}}
+  </nav>
+  <main>
+    {{range .Groups}}
+    <h1>{{.Dir}}</h1>
+    <div class="cards">
+      {{range .Files}}
+      <a class="card" id="{{.ID}}" href="{{.PageLink}}">
+        <h3>{{.Filename}}</h3>
+        {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+        <img loading="lazy" src="{{.SvgLink}}" alt="{{.Filename}}" />
+      </a>
+      {{end}}
+    </div>
+    {{end}}
+  </main>
+{{end}}`
+
+const diagramPageTemplate = `{{define "title"}}{{.Filename}} - gopuml{{end}}
+{{define "reload"}}{{.PagePath}}{{end}}
+{{define "body"}}
+  <main>
+    {{if .Error.Message}}
+    <div class="error-overlay">
+      <strong>Error{{if .Error.Line}} on <a href="#L{{.Error.Line}}">line {{.Error.Line}}</a>{{end}}:</strong>
+      {{.Error.Message}}
+      {{if .IsLastValid}}<br/><small>Showing the last version without errors.</small>{{end}}
+    </div>
+    {{end}}
+    <p><a href="/">&larr; All diagrams</a></p>
+    <h1>{{.Filename}}</h1>
+    <p>{{.Dir}}</p>
+
+    <div class="buttons">
+      Format:
+      {{range .Formats}}
+      <a href="{{.Link}}"{{if eq .Format $.Format}} class="selected"{{end}}>{{.Format}}</a>
+      {{end}}
+    </div>
+
+    <div class="diagram">
+      {{if .IsTextView}}
+      <iframe src="{{.Link}}" title="{{.Filename}}"></iframe>
+      {{else}}
+      <img src="{{.Link}}" alt="{{.Filename}}" />
+      {{end}}
+    </div>
+
+    <div class="buttons">
+      Download:
+      {{range .Downloads}}
+      <a href="{{.Link}}" download="{{$.Basename}}.{{.Format}}">.{{.Format}}</a>
+      {{end}}
+    </div>
+
+    <h2>Source</h2>
+    <pre class="source">{{range .Source}}<span id="{{.ID}}"{{if .IsError}} class="error"{{end}}>{{.Text}}</span>{{end}}</pre>
+  </main>
+{{end}}`
@@ -0,0 +1,271 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+	"github.com/lonnblad/gopuml/example"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+func Test_RunServeCommandShutdown(t *testing.T) {
+	plantuml := plantumltest.NewServer()
+	defer plantuml.Close()
+
+	file := t.TempDir() + "/example.puml"
+	require.Nil(t, os.WriteFile(file, []byte(example.PUML()), 0600))
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err)
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	require.Nil(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := internal.CreateServeCmd()
+	cmd.SetArgs([]string{"--port", port, "--server", plantuml.URL, file})
+
+	var stdout bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&bytes.Buffer{})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cmd.ExecuteContext(ctx)
+	}()
+
+	url := "http://localhost:" + port + "/"
+
+	require.Eventually(t, func() bool {
+		response, err := http.Get(url) // nolint: gosec, noctx
+		if err != nil {
+			return false
+		}
+
+		response.Body.Close()
+
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	longPoll := make(chan int, 1)
+
+	go func() {
+		request, _ := http.NewRequest(http.MethodHead, url, nil) // nolint: noctx
+		request.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(time.RFC1123))
+
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			longPoll <- 0
+			return
+		}
+
+		response.Body.Close()
+		longPoll <- response.StatusCode
+	}()
+
+	// Gives the long-polling request time to reach the server.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err = <-done:
+		require.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the server wasn't shut down")
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, <-longPoll)
+	assert.Contains(t, stdout.String(), "Shutting down the server")
+}
+
+func Test_RunServeCommandReloadsFilesSavedByRename(t *testing.T) {
+	plantuml := plantumltest.NewServer()
+	defer plantuml.Close()
+
+	dir := t.TempDir()
+	file := dir + "/example.puml"
+	require.Nil(t, os.WriteFile(file, []byte(example.PUML()), 0600))
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err)
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	require.Nil(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := internal.CreateServeCmd()
+	cmd.SetArgs([]string{"--port", port, "--server", plantuml.URL, file})
+
+	// The event handler and the server write to the output concurrently.
+	var output syncBuffer
+
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cmd.ExecuteContext(ctx)
+	}()
+
+	rendered := func(content string) func() bool {
+		return func() bool {
+			for _, request := range plantuml.Requests() {
+				if request.Source == content {
+					return true
+				}
+			}
+
+			return false
+		}
+	}
+
+	require.Eventually(t, rendered(example.PUML()), 5*time.Second, 10*time.Millisecond)
+
+	// Every save replaces the file, which only reloads if the watch is added again for the new file.
+	for _, message := range []string{"first", "second"} {
+		content := "@startuml\nBob -> Alice : " + message + "\n@enduml\n"
+
+		require.Nil(t, os.WriteFile(dir+"/example.puml.tmp", []byte(content), 0600))
+		require.Nil(t, os.Rename(dir+"/example.puml.tmp", file))
+
+		require.Eventually(t, rendered(content), 5*time.Second, 10*time.Millisecond, message)
+	}
+
+	cancel()
+
+	select {
+	case err = <-done:
+		require.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the server wasn't shut down")
+	}
+
+	assert.Contains(t, output.String(), "modified file: "+file)
+}
+
+func Test_RunServeCommandDownloads(t *testing.T) {
+	plantuml := plantumltest.NewServer()
+	defer plantuml.Close()
+
+	file := t.TempDir() + "/example.puml"
+	require.Nil(t, os.WriteFile(file, []byte(example.PUML()), 0600))
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err)
+
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+	require.Nil(t, listener.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cmd := internal.CreateServeCmd()
+	cmd.SetArgs([]string{"--port", port, "--server", plantuml.URL, file})
+
+	cmd.SetOut(&syncBuffer{})
+	cmd.SetErr(&syncBuffer{})
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- cmd.ExecuteContext(ctx)
+	}()
+
+	url := "http://localhost:" + port + "/d/" + strings.TrimPrefix(filepath.ToSlash(file), "/")
+
+	get := func(query string) (*http.Response, []byte) {
+		response, err := http.Get(url + query) // nolint: gosec, noctx
+		require.Nil(t, err)
+
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		require.Nil(t, err)
+
+		return response, body
+	}
+
+	require.Eventually(t, func() bool {
+		response, err := http.Get(url) // nolint: gosec, noctx
+		if err != nil {
+			return false
+		}
+
+		response.Body.Close()
+
+		return response.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The download buttons link to the local server, since browsers ignore the download attribute of cross-origin links.
+	_, page := get("")
+	assert.Contains(t, string(page), `href="?download=svg"`)
+	assert.NotContains(t, string(page), `href="`+plantuml.URL+`/svg/`)
+
+	response, body := get("?download=svg")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "attachment; filename=example.svg", response.Header.Get("Content-Disposition"))
+	assert.Equal(t, "image/svg+xml", response.Header.Get("Content-Type"))
+	assert.Equal(t, plantumltest.Render("svg", []byte(example.PUML())), body)
+
+	response, _ = get("?download=png")
+	assert.Equal(t, "attachment; filename=example.png", response.Header.Get("Content-Disposition"))
+
+	response, _ = get("?download=pdf")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	plantuml.FailNext(plantumltest.Failure{StatusCode: http.StatusNotFound})
+
+	response, _ = get("?download=svg")
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	assert.Empty(t, response.Header.Get("Content-Disposition"))
+
+	cancel()
+
+	select {
+	case err = <-done:
+		require.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("the server wasn't shut down")
+	}
+}
+
+// syncBuffer is a buffer which can be written to concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
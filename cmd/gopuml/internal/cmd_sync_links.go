@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/docs"
+	"github.com/lonnblad/gopuml/internal/fsutil"
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+type syncLinksOptions struct {
+	Check bool
+}
+
+const flagUsageSyncLinksCheck = `check that the links are up to date without writing the files,
+fails if any file would change
+ `
+
+// syncLinksConfigFlags are the flags of sync-links which are set from the environment or the configuration.
+var syncLinksConfigFlags = []string{flagCheck}
+
+// CreateSyncLinksCmd creates the sync-links subcommand.
+// The command finds marker comments in documents and rewrites the link following the
+// marker with a link to the current version of the source file in the marker.
+func CreateSyncLinksCmd() cobra.Command {
+	opts := syncLinksOptions{}
+
+	syncLinksCmd := cobra.Command{
+		Use:   "sync-links [documents or directories]",
+		Short: "Keeps Plant UML links in documents in sync with their source files",
+		Long: `Keeps Plant UML links in Markdown, AsciiDoc and HTML documents in sync with their source files.
+
+The link on the line following a marker comment is rewritten with a link to the
+current version of the source file, the source is relative to the document:
+  <!-- gopuml:src=diagrams/auth.puml format=svg -->
+  // gopuml:src=diagrams/auth.puml format=svg
+The second form is used in AsciiDoc. The format is optional, the server
+and the format of the current link are kept unless the format is set.`,
+		Example: `  gopuml sync-links README.md
+  gopuml sync-links --check docs`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: syncLinksCmdRunFunc(&opts),
+	}
+
+	syncLinksCmd.Flags().BoolVar(&opts.Check, flagCheck, opts.Check, flagUsageSyncLinksCheck)
+	addConfigFlag(&syncLinksCmd)
+
+	return syncLinksCmd
+}
+
+var linkDocumentExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".adoc": true, ".asciidoc": true, ".html": true, ".htm": true,
+}
+
+func syncLinksCmdRunFunc(opts *syncLinksOptions) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, syncLinksConfigFlags...); err != nil {
+			return err
+		}
+
+		filepaths, err := findDocuments(args, linkDocumentExtensions)
+		if err != nil {
+			return err
+		}
+
+		var (
+			diagnostics []diagnostic.Diagnostic
+			outOfDate   []string
+		)
+
+		for _, path := range filepaths {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			updated, ds, err := syncLinks(path, content)
+			if err != nil {
+				return err
+			}
+
+			diagnostics = append(diagnostics, ds...)
+
+			if bytes.Equal(content, updated) {
+				continue
+			}
+
+			if opts.Check {
+				outOfDate = append(outOfDate, displayPath(path))
+				continue
+			}
+
+			const readWriteMode = 0600
+			if _, err = fsutil.WriteFile(path, updated, readWriteMode); err != nil {
+				return fmt.Errorf("couldn't write file: %w", err)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), "updated file:", displayPath(path))
+		}
+
+		if err = diagnostic.Write(cmd.ErrOrStderr(), diagnostic.FormatText, diagnostics); err != nil {
+			return err
+		}
+
+		for _, path := range outOfDate {
+			cmd.PrintErrln(path + ": out of date")
+		}
+
+		if len(diagnostics) == 0 && len(outOfDate) == 0 {
+			return nil
+		}
+
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+
+		return fmt.Errorf("found %d problem(s) and %d file(s) out of date", len(diagnostics), len(outOfDate))
+	}
+}
+
+// syncLinks rewrites the links following the markers in the document.
+func syncLinks(path string, content []byte) ([]byte, []diagnostic.Diagnostic, error) {
+	var diagnostics []diagnostic.Diagnostic
+
+	lines := docs.SplitLines(string(content))
+
+	for _, marker := range docs.FindMarkers(lines) {
+		report := func(message string) {
+			diagnostics = append(diagnostics, diagnostic.Diagnostic{
+				File: displayPath(path), Line: marker.Line + 1, Message: message,
+			})
+		}
+
+		linkedLine := docs.LinkedLine(lines, marker)
+		if linkedLine < 0 {
+			report("no Plant UML link found after the marker")
+			continue
+		}
+
+		srcPath := marker.Src
+		if !filepath.IsAbs(srcPath) {
+			srcPath = filepath.Join(filepath.Dir(path), srcPath)
+		}
+
+		src, err := source.Load(srcPath)
+		if err != nil {
+			report(fmt.Sprintf("couldn't load the source: %s", err))
+			continue
+		}
+
+		if linksUpToDate(lines[linkedLine], marker.Format, src.Content) {
+			continue
+		}
+
+		encoded, err := compressAndEncode(src.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lines[linkedLine] = docs.ReplaceLinks(lines[linkedLine], marker.Format, string(encoded))
+	}
+
+	return []byte(strings.Join(lines, lineEnding(content))), diagnostics, nil
+}
+
+// linksUpToDate returns true if every link in the line decodes to the content and has the format,
+// an empty format matches any format. The links are compared by their decoded source, since
+// other encoders, like the Plant UML server, compress the same source differently.
+func linksUpToDate(line, format string, content []byte) bool {
+	for _, link := range docs.FindLinks(line) {
+		if format != "" && link.Format != format {
+			return false
+		}
+
+		decoded, err := docs.DecodeLink(link)
+		if err != nil || !bytes.Equal(decoded, content) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,122 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
+	"github.com/lonnblad/gopuml/example"
+)
+
+func Test_RunSyncLinksCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	docFile := tempDir + "/" + "README.md"
+
+	err := os.MkdirAll(tempDir+"/diagrams", 0700)
+	require.Nil(t, err)
+
+	err = os.WriteFile(tempDir+"/diagrams/example.puml", []byte(example.PUML()), 0600)
+	require.Nil(t, err)
+
+	doc := "# Example\n" +
+		"<!-- gopuml:src=diagrams/example.puml format=png -->\n" +
+		"![example](https://plantuml.example.com/plantuml/svg/stale)\n"
+
+	err = os.WriteFile(docFile, []byte(doc), 0600)
+	require.Nil(t, err)
+
+	compressed, err := gopuml.Deflate([]byte(example.PUML()))
+	require.Nil(t, err)
+
+	encoded := string(gopuml.Encode(compressed))
+
+	_, stderr, err := executeSyncLinksCmd("--check", tempDir)
+	assert.NotNil(t, err)
+	assert.Equal(t, docFile+": out of date\n", stderr)
+
+	stdout, stderr, err := executeSyncLinksCmd(docFile)
+	require.Nil(t, err)
+	assert.Empty(t, stderr)
+	assert.Equal(t, "updated file: "+docFile+"\n", stdout)
+
+	actualDoc, err := os.ReadFile(docFile)
+	require.Nil(t, err)
+
+	expectedDoc := "# Example\n" +
+		"<!-- gopuml:src=diagrams/example.puml format=png -->\n" +
+		"![example](https://plantuml.example.com/plantuml/png/" + encoded + ")\n"
+	assert.Equal(t, expectedDoc, string(actualDoc))
+
+	stdout, _, err = executeSyncLinksCmd("--check", tempDir)
+	require.Nil(t, err)
+	assert.Empty(t, stdout)
+
+	// A link to the same source encoded by another encoder is up to date.
+	hexDoc := "# Example\n" +
+		"<!-- gopuml:src=diagrams/example.puml -->\n" +
+		"![example](https://plantuml.example.com/plantuml/png/~h" + hex.EncodeToString([]byte(example.PUML())) + ")\n"
+
+	err = os.WriteFile(docFile, []byte(hexDoc), 0600)
+	require.Nil(t, err)
+
+	stdout, stderr, err = executeSyncLinksCmd(docFile)
+	require.Nil(t, err)
+	assert.Empty(t, stderr)
+	assert.Empty(t, stdout)
+
+	_, _, err = executeSyncLinksCmd("--check", tempDir)
+	require.Nil(t, err)
+
+	actualDoc, err = os.ReadFile(docFile)
+	require.Nil(t, err)
+	assert.Equal(t, hexDoc, string(actualDoc))
+
+	err = os.WriteFile(docFile, []byte("<!-- gopuml:src=missing.puml -->\n![x](https://srv/svg/abc)\n"), 0600)
+	require.Nil(t, err)
+
+	_, stderr, err = executeSyncLinksCmd(docFile)
+	assert.NotNil(t, err)
+	assert.Contains(t, stderr, docFile+":1: couldn't load the source")
+}
+
+func executeSyncLinksCmd(args ...string) (string, string, error) {
+	cmd := internal.CreateSyncLinksCmd()
+	cmd.SetArgs(args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+
+	err := cmd.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
+
+func Test_RunSyncLinksCommandWithConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	docFile := tempDir + "/" + "README.md"
+
+	require.Nil(t, os.WriteFile(tempDir+"/example.puml", []byte(example.PUML()), 0600))
+
+	doc := "<!-- gopuml:src=example.puml -->\n![example](https://plantuml.example.com/plantuml/svg/stale)\n"
+	require.Nil(t, os.WriteFile(docFile, []byte(doc), 0600))
+
+	t.Setenv("GOPUML_CHECK", "true")
+
+	_, stderr, err := executeSyncLinksCmd(docFile)
+	assert.NotNil(t, err)
+	assert.Equal(t, docFile+": out of date\n", stderr)
+
+	configFile := tempDir + "/" + ".gopuml.yaml"
+	require.Nil(t, os.WriteFile(configFile, []byte("unknown: true\n"), 0600))
+
+	_, _, err = executeSyncLinksCmd("--config", configFile, docFile)
+	assert.ErrorContains(t, err, "couldn't parse config file")
+}
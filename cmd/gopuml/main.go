@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/lonnblad/gopuml/cmd/gopuml/internal"
 )
@@ -11,12 +14,42 @@ var version string = "v0.2.1"
 func main() {
 	rootCmd := internal.CreateRootCmd()
 	buildCmd := internal.CreateBuildCmd()
+	extractCmd := internal.CreateExtractCmd()
+	fmtCmd := internal.CreateFmtCmd()
+	genCmd := internal.CreateGenCmd()
+	genClassCmd := internal.CreateGenClassCmd()
+	genCommentsCmd := internal.CreateGenCommentsCmd()
+	genDepsCmd := internal.CreateGenDepsCmd()
+	genModGraphCmd := internal.CreateGenModGraphCmd()
+	genSequenceCmd := internal.CreateGenSequenceCmd()
+	hookCmd := internal.CreateHookCmd()
+	hookInstallCmd := internal.CreateHookInstallCmd()
+	hookRunCmd := internal.CreateHookRunCmd()
+	hookUninstallCmd := internal.CreateHookUninstallCmd()
+	lintCmd := internal.CreateLintCmd()
+	markdownCmd := internal.CreateMarkdownCmd()
 	serveCmd := internal.CreateServeCmd()
+	syncLinksCmd := internal.CreateSyncLinksCmd()
 	versionCmd := internal.CreateVersionCmd(version)
 
-	rootCmd.AddCommand(&buildCmd, &serveCmd, &versionCmd)
+	genCmd.AddCommand(&genClassCmd, &genCommentsCmd, &genDepsCmd, &genModGraphCmd, &genSequenceCmd)
+	hookCmd.AddCommand(&hookInstallCmd, &hookRunCmd, &hookUninstallCmd)
+	rootCmd.AddCommand(&buildCmd, &extractCmd, &fmtCmd, &genCmd, &hookCmd, &lintCmd, &markdownCmd, &serveCmd, &syncLinksCmd, &versionCmd)
 
-	if err := rootCmd.Execute(); err != nil {
+	// The commands are cancelled with the context on an interrupt,
+	// a second interrupt terminates the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+
+	stop()
+
+	if err != nil {
 		os.Exit(1)
 	}
 
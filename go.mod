@@ -1,20 +1,25 @@
 module github.com/lonnblad/gopuml
 
-go 1.20
+// Go 1.22 is required by golang.org/x/tools, which loads the packages for gen.
+// The releases which support Go 1.20 don't build with current Go toolchains.
+go 1.22.0
 
 require (
 	github.com/fsnotify/fsnotify v1.6.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v1.7.0
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.8.4
+	golang.org/x/mod v0.21.0
+	golang.org/x/tools v0.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
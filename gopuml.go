@@ -10,11 +10,18 @@
 //	compressed, err := gopuml.Deflate(rawContent)
 //	...
 //	encoded := gopuml.Encode(compressed)
+//
+// An example where encoded content, like the last part of a link, is decoded and decompressed.
+//
+//	compressed, err := gopuml.Decode(encoded)
+//	...
+//	rawContent, err := gopuml.Inflate(compressed)
 package gopuml
 
 import (
 	"bytes"
 	"compress/flate"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -42,6 +49,21 @@ func Deflate(input []byte) (_ []byte, err error) {
 	return b.Bytes(), nil
 }
 
+// Inflate will decompress input compressed with the Deflate compression algorithm.
+// A truncated input is decompressed as far as possible.
+func Inflate(input []byte) (_ []byte, err error) {
+	zr := flate.NewReader(bytes.NewReader(input))
+	defer zr.Close()
+
+	output, err := io.ReadAll(zr)
+	if err != nil && !(errors.Is(err, io.ErrUnexpectedEOF) && len(output) > 0) {
+		err = fmt.Errorf("couldn't decompress input: %w", err)
+		return
+	}
+
+	return output, nil
+}
+
 const encodeMapping = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
 
 // Encode will encode the input in a similar way as base64.
@@ -70,3 +92,36 @@ func Encode(input []byte) []byte {
 
 	return buffer.Bytes()
 }
+
+// Decode will decode input encoded with Encode.
+// Since Encode pads the input, the output can have up to two trailing zero bytes.
+func Decode(input []byte) (_ []byte, err error) {
+	if len(input)%4 != 0 { // nolint: gomnd
+		err = fmt.Errorf("invalid encoded length: %d", len(input))
+		return
+	}
+
+	output := make([]byte, 0, len(input)/4*3) // nolint: gomnd
+
+	for i := 0; i < len(input); i += 4 {
+		var b [4]byte
+
+		for j := range b {
+			idx := bytes.IndexByte([]byte(encodeMapping), input[i+j])
+			if idx < 0 {
+				err = fmt.Errorf("invalid encoded character: %q", input[i+j])
+				return
+			}
+
+			b[j] = byte(idx)
+		}
+
+		output = append(output,
+			(b[0]<<2)|(b[1]>>4),       // nolint: gomnd
+			((b[1]&0xf)<<4)|(b[2]>>2), // nolint: gomnd
+			((b[2]&0x3)<<6)|b[3],      // nolint: gomnd
+		)
+	}
+
+	return output, nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/lonnblad/gopuml"
 )
@@ -24,3 +25,38 @@ func Test_Encode(t *testing.T) {
 	actualOutput := gopuml.Encode(input)
 	assert.Equal(t, expectedOutput, actualOutput)
 }
+
+func Test_Decode(t *testing.T) {
+	input := []byte{0x72, 0x28, 0x2e, 0x49, 0x2c, 0x2a, 0x29, 0xcd, 0xcd, 0x51, 0x70, 0xad, 0x48, 0xcc, 0x2d}
+
+	actualOutput, actualErr := gopuml.Decode(gopuml.Encode(input))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, input, actualOutput)
+
+	actualOutput, actualErr = gopuml.Decode(gopuml.Encode(input[:14]))
+	assert.Nil(t, actualErr)
+	assert.Equal(t, append(input[:14:14], 0x0), actualOutput)
+
+	_, actualErr = gopuml.Decode([]byte("abc"))
+	assert.NotNil(t, actualErr)
+
+	_, actualErr = gopuml.Decode([]byte("ab=c"))
+	assert.NotNil(t, actualErr)
+}
+
+func Test_Inflate(t *testing.T) {
+	input := []byte("@startuml Example\nBob -> Alice : hello\n@enduml")
+
+	compressed, err := gopuml.Deflate(input)
+	require.Nil(t, err)
+
+	decoded, err := gopuml.Decode(gopuml.Encode(compressed))
+	require.Nil(t, err)
+
+	actualOutput, actualErr := gopuml.Inflate(decoded)
+	assert.Nil(t, actualErr)
+	assert.Equal(t, input, actualOutput)
+
+	_, actualErr = gopuml.Inflate([]byte("not compressed"))
+	assert.NotNil(t, actualErr)
+}
@@ -0,0 +1,237 @@
+// Package changes finds the Plant UML files affected by changes in a git repository.
+//
+// A file is affected when it, or any file it includes with !include or !include_once,
+// has changed since a git ref.
+package changes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+// ErrNotRepository is returned when the directory isn't inside a git repository,
+// or when git isn't installed.
+var ErrNotRepository = errors.New("not inside a git repository")
+
+// Since returns the absolute paths of the files in the repository of dir which have changed since the ref,
+// including uncommitted and untracked files. Changes are found from the merge base of the ref and HEAD,
+// so commits on the ref which aren't in HEAD, like new commits on a base branch, aren't changes.
+func Since(dir, ref string) ([]string, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+
+	root = strings.TrimSpace(root)
+
+	if _, err = git(dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref: [%s]", ref)
+	}
+
+	mergeBase, err := git(root, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("no common commit of [%s] and HEAD: %w", ref, err)
+	}
+
+	changed, err := git(root, "diff", "--name-only", "-z", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := git(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for _, name := range strings.Split(changed+untracked, "\x00") {
+		if name != "" {
+			paths = append(paths, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+
+	return paths, nil
+}
+
+// Staged returns the absolute paths of the files in the repository of dir which are added,
+// copied, modified or renamed in the index.
+func Staged(dir string) ([]string, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+
+	root = strings.TrimSpace(root)
+
+	staged, err := git(root, "diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for _, name := range strings.Split(staged, "\x00") {
+		if name != "" {
+			paths = append(paths, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+
+	return paths, nil
+}
+
+// ReadStaged returns the content of the file in the index of its repository, which is the content to be committed.
+// An error wrapping fs.ErrNotExist is returned when the file isn't in the index.
+func ReadStaged(path string) ([]byte, error) {
+	dir, name := filepath.Split(path)
+
+	entry, err := git(dir, "--literal-pathspecs", "ls-files", "--stage", "-z", "--", name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The entry is formatted like "<mode> <object> <stage>\t<file>", a directory lists the files in it.
+	info, file, _ := strings.Cut(strings.Split(entry, "\x00")[0], "\t")
+
+	fields := strings.Fields(info)
+	if len(fields) < 3 || file != name { // nolint: gomnd
+		return nil, fmt.Errorf("%s isn't staged: %w", path, fs.ErrNotExist)
+	}
+
+	content, err := git(dir, "cat-file", "blob", fields[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(content), nil
+}
+
+// Unstaged returns the absolute paths of the files, in the repository of dir,
+// which have changes in the working tree which aren't staged.
+func Unstaged(dir string, paths ...string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotRepository
+	}
+
+	root = strings.TrimSpace(root)
+
+	unstaged, err := git(dir, append([]string{"--literal-pathspecs", "diff", "--name-only", "-z", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+
+	for _, name := range strings.Split(unstaged, "\x00") {
+		if name != "" {
+			result = append(result, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+
+	return result, nil
+}
+
+// Stage adds the files to the index of the repository of dir.
+func Stage(dir string, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	_, err := git(dir, append([]string{"add", "--"}, paths...)...)
+
+	return err
+}
+
+// Affected returns the files which have changed, or which include a changed file,
+// directly or through other included files. The order of the files is kept.
+func Affected(files, changed []string) ([]string, error) {
+	isChanged := make(map[string]bool, len(changed))
+
+	for _, path := range changed {
+		isChanged[canonical(path)] = true
+	}
+
+	var affected []string
+
+	for _, file := range files {
+		ok, err := includesChange(canonical(file), isChanged, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			affected = append(affected, file)
+		}
+	}
+
+	return affected, nil
+}
+
+// includesChange reports if the file has changed or includes a changed file,
+// visited holds the files already checked, which handles include cycles.
+func includesChange(path string, isChanged, visited map[string]bool) (bool, error) {
+	if isChanged[path] {
+		return true, nil
+	}
+
+	if visited[path] {
+		return false, nil
+	}
+
+	visited[path] = true
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	for _, include := range source.Includes(filepath.Dir(path), content) {
+		if ok, err := includesChange(canonical(include), isChanged, visited); ok || err != nil {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// canonical returns the absolute path with symbolic links resolved,
+// since git reports paths in the resolved repository root.
+func canonical(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+
+	return path
+}
+
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,172 @@
+package changes_test
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/changes"
+)
+
+func Test_SinceAndAffected(t *testing.T) {
+	dir := newRepository(t)
+
+	writeFile(t, dir, "skin.iuml", "skinparam monochrome true\n")
+	writeFile(t, dir, "common.iuml", "!include skin.iuml\n")
+	writeFile(t, dir, "a.puml", "@startuml\n!include common.iuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, dir, "b.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, dir, "c.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-m", "initial")
+
+	changed, err := changes.Since(dir, "HEAD")
+	require.Nil(t, err)
+	assert.Empty(t, changed)
+
+	writeFile(t, dir, "skin.iuml", "skinparam monochrome false\n")
+	writeFile(t, dir, "c.puml", "@startuml\nAlice -> Bob\n@enduml\n")
+	git(t, dir, "commit", "-am", "change")
+	writeFile(t, dir, "d.puml", "@startuml\nBob -> Alice\n@enduml\n")
+
+	changed, err = changes.Since(filepath.Join(dir), "HEAD~1")
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{resolve(t, dir, "c.puml"), resolve(t, dir, "d.puml"), resolve(t, dir, "skin.iuml")}, changed)
+
+	files := []string{filepath.Join(dir, "a.puml"), filepath.Join(dir, "b.puml"), filepath.Join(dir, "c.puml"), filepath.Join(dir, "d.puml")}
+
+	affected, err := changes.Affected(files, changed)
+	require.Nil(t, err)
+	assert.Equal(t, []string{files[0], files[2], files[3]}, affected)
+
+	_, err = changes.Since(dir, "does-not-exist")
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, changes.ErrNotRepository)
+
+	_, err = changes.Since(t.TempDir(), "HEAD")
+	assert.ErrorIs(t, err, changes.ErrNotRepository)
+}
+
+func Test_SinceDivergedBranch(t *testing.T) {
+	dir := newRepository(t)
+
+	writeFile(t, dir, "a.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-m", "initial")
+	git(t, dir, "branch", "-M", "main")
+	git(t, dir, "checkout", "--quiet", "-b", "feature")
+
+	writeFile(t, dir, "b.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-m", "feature")
+
+	git(t, dir, "checkout", "--quiet", "main")
+	writeFile(t, dir, "a.puml", "@startuml\nAlice -> Bob\n@enduml\n")
+	writeFile(t, dir, "c.puml", "@startuml\nAlice -> Bob\n@enduml\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-m", "main")
+	git(t, dir, "checkout", "--quiet", "feature")
+
+	// The changes on main since the feature branch was created aren't changes of the feature branch.
+	changed, err := changes.Since(dir, "main")
+	require.Nil(t, err)
+	assert.Equal(t, []string{resolve(t, dir, "b.puml")}, changed)
+}
+
+func newRepository(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	git(t, dir, "init", "--quiet")
+	git(t, dir, "config", "user.name", "gopuml")
+	git(t, dir, "config", "user.email", "gopuml@example.com")
+
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(output))
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+}
+
+func resolve(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	require.Nil(t, err)
+
+	return filepath.Join(resolved, name)
+}
+
+func Test_StagedAndStage(t *testing.T) {
+	dir := newRepository(t)
+
+	writeFile(t, dir, "a.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, dir, "b.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-m", "initial")
+
+	staged, err := changes.Staged(dir)
+	require.Nil(t, err)
+	assert.Empty(t, staged)
+
+	writeFile(t, dir, "a.puml", "@startuml\nAlice -> Bob\n@enduml\n")
+	writeFile(t, dir, "c.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, dir, "d.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	require.Nil(t, os.Remove(filepath.Join(dir, "b.puml")))
+	require.Nil(t, changes.Stage(dir, "a.puml", "b.puml", "c.puml"))
+
+	staged, err = changes.Staged(dir)
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{resolve(t, dir, "a.puml"), resolve(t, dir, "c.puml")}, staged)
+
+	_, err = changes.Staged(t.TempDir())
+	assert.ErrorIs(t, err, changes.ErrNotRepository)
+}
+
+func Test_ReadStagedAndUnstaged(t *testing.T) {
+	dir := newRepository(t)
+
+	writeFile(t, dir, "a.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, dir, "b.puml", "@startuml\nBob -> Alice\n@enduml\n")
+	require.Nil(t, os.Mkdir(filepath.Join(dir, "skins"), 0700))
+	writeFile(t, dir, "skins/skin.iuml", "skinparam monochrome true\n")
+	git(t, dir, "add", "-A")
+
+	writeFile(t, dir, "a.puml", "@startuml\nAlice -> Bob\n@enduml\n")
+	writeFile(t, dir, "c.puml", "@startuml\nBob -> Alice\n@enduml\n")
+
+	content, err := changes.ReadStaged(filepath.Join(dir, "a.puml"))
+	require.Nil(t, err)
+	assert.Equal(t, "@startuml\nBob -> Alice\n@enduml\n", string(content))
+
+	_, err = changes.ReadStaged(filepath.Join(dir, "c.puml"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	// A directory isn't a staged file, even though the files in it are staged.
+	_, err = changes.ReadStaged(filepath.Join(dir, "skins"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	unstaged, err := changes.Unstaged(dir, "a.puml", "b.puml", "c.puml")
+	require.Nil(t, err)
+	assert.Equal(t, []string{resolve(t, dir, "a.puml")}, unstaged)
+}
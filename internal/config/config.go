@@ -0,0 +1,243 @@
+// Package config loads the project configuration file, .gopuml.yaml.
+//
+// The file holds defaults for the flags of the commands and named targets, like:
+//
+//	server: https://plantuml.example.com/plantuml
+//	format: svg
+//	targets:
+//	  docs:
+//	    inputs: [docs/**/*.puml]
+//	    formats: [svg, png]
+//	    outDir: docs/img
+//	lint:
+//	  rules:
+//	    hardcoded-color: false
+//	hook:
+//	  autoStage: true
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Filename is the name of the configuration file.
+const Filename = ".gopuml.yaml"
+
+// EnvPrefix is the prefix of the environment variables which set the defaults for flags,
+// like GOPUML_SERVER for --server.
+const EnvPrefix = "GOPUML_"
+
+// Config is the project configuration.
+type Config struct {
+	// Path is the path of the file the configuration was loaded from, it's empty if no file was found.
+	Path string `yaml:"-"`
+
+	Server      string `yaml:"server"`
+	Format      string `yaml:"format"`
+	Style       string `yaml:"style"`
+	ErrorFormat string `yaml:"errorFormat"`
+	Quiet       *bool  `yaml:"quiet"`
+	Port        string `yaml:"port"`
+	Sort        string `yaml:"sort"`
+
+	// The paths of the files used by the HTTP client are relative to the configuration file.
+	TokenFile  string `yaml:"tokenFile"`
+	CACert     string `yaml:"caCert"`
+	ClientCert string `yaml:"clientCert"`
+	ClientKey  string `yaml:"clientKey"`
+	Proxy      string `yaml:"proxy"`
+	Timeout    string `yaml:"timeout"`
+
+	Method        string `yaml:"method"`
+	MaxLinkLength string `yaml:"maxLinkLength"`
+
+	Retries          string `yaml:"retries"`
+	Rate             string `yaml:"rate"`
+	BreakerThreshold string `yaml:"breakerThreshold"`
+
+	Lint Lint `yaml:"lint"`
+	Hook Hook `yaml:"hook"`
+
+	Targets map[string]Target `yaml:"targets"`
+}
+
+// Lint configures the lint rules.
+type Lint struct {
+	// Rules enables or disables rules by name, rules which aren't listed use their default.
+	Rules           map[string]bool `yaml:"rules"`
+	MaxParticipants string          `yaml:"maxParticipants"`
+	// Skin is the file every diagram should include, relative to the configuration file.
+	Skin string `yaml:"skin"`
+}
+
+// Hook configures the checks of the git pre-commit hook.
+type Hook struct {
+	// AutoStage writes and stages formatted files and regenerated outputs, instead of failing.
+	AutoStage *bool `yaml:"autoStage"`
+	// Skip are the checks to skip, any of fmt, lint and build.
+	Skip []string `yaml:"skip"`
+}
+
+// Target is a named set of files to build.
+type Target struct {
+	// Inputs are glob patterns of the files to build, relative to the configuration file.
+	// A ** matches any number of directories.
+	Inputs []string `yaml:"inputs"`
+	// Formats are the formats to build the files in.
+	Formats []string `yaml:"formats"`
+	// OutDir is the directory to write the files to, relative to the configuration file.
+	// The files keep their path relative to the directory before the first wildcard of their input,
+	// and they are written next to the inputs if it's empty.
+	OutDir string `yaml:"outDir"`
+}
+
+// Find finds the configuration file in the directory or its closest parent,
+// it returns an empty path if there is no configuration file.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, Filename)
+
+		if _, err = os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// Load loads the configuration file, unknown fields are reported as errors.
+func Load(path string) (Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var cfg Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err = decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("couldn't parse config file: %s: %w", path, err)
+	}
+
+	if cfg.Path, err = filepath.Abs(path); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Dir returns the directory of the configuration file,
+// which paths in the configuration are relative to.
+func (cfg Config) Dir() string {
+	if cfg.Path == "" {
+		return "."
+	}
+
+	return filepath.Dir(cfg.Path)
+}
+
+// path returns the path relative to the directory of the configuration file.
+func (cfg Config) path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(cfg.Dir(), path)
+}
+
+// Defaults returns the defaults for flags by flag name.
+func (cfg Config) Defaults() map[string]string {
+	defaults := make(map[string]string)
+
+	for flag, value := range map[string]string{
+		"server":            cfg.Server,
+		"format":            cfg.Format,
+		"style":             cfg.Style,
+		"error-format":      cfg.ErrorFormat,
+		"port":              cfg.Port,
+		"sort":              cfg.Sort,
+		"token-file":        cfg.path(cfg.TokenFile),
+		"ca-cert":           cfg.path(cfg.CACert),
+		"client-cert":       cfg.path(cfg.ClientCert),
+		"client-key":        cfg.path(cfg.ClientKey),
+		"proxy":             cfg.Proxy,
+		"timeout":           cfg.Timeout,
+		"method":            cfg.Method,
+		"max-link-length":   cfg.MaxLinkLength,
+		"retries":           cfg.Retries,
+		"rate":              cfg.Rate,
+		"breaker-threshold": cfg.BreakerThreshold,
+		"max-participants":  cfg.Lint.MaxParticipants,
+		"skin":              cfg.path(cfg.Lint.Skin),
+	} {
+		if value != "" {
+			defaults[flag] = value
+		}
+	}
+
+	if cfg.Quiet != nil {
+		defaults["quiet"] = strconv.FormatBool(*cfg.Quiet)
+	}
+
+	if cfg.Hook.AutoStage != nil {
+		defaults["auto-stage"] = strconv.FormatBool(*cfg.Hook.AutoStage)
+	}
+
+	if len(cfg.Hook.Skip) > 0 {
+		defaults["skip"] = strings.Join(cfg.Hook.Skip, ",")
+	}
+
+	return defaults
+}
+
+// Target returns the target with the name.
+func (cfg Config) Target(name string) (Target, error) {
+	target, ok := cfg.Targets[name]
+	if ok {
+		return target, nil
+	}
+
+	names := make([]string, 0, len(cfg.Targets))
+
+	for name := range cfg.Targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if cfg.Path == "" {
+		return Target{}, fmt.Errorf("unknown target: [%s], no %s found", name, Filename)
+	}
+
+	return Target{}, fmt.Errorf("unknown target: [%s], the targets in %s are: [%s]", name, cfg.Path, strings.Join(names, ", "))
+}
+
+// EnvName returns the name of the environment variable which sets the default for the flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
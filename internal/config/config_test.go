@@ -0,0 +1,109 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/config"
+)
+
+func Test_FindAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "docs", "diagrams")
+
+	writeFile(t, filepath.Join(dir, config.Filename), `server: https://plantuml.example.com
+format: png
+quiet: true
+caCert: certs/ca.pem
+timeout: 30s
+lint:
+  rules:
+    hardcoded-color: false
+  maxParticipants: "8"
+  skin: skin.iuml
+hook:
+  autoStage: true
+  skip: [lint, build]
+targets:
+  docs:
+    inputs: [docs/**/*.puml]
+    formats: [svg, png]
+    outDir: docs/img
+`)
+	require.Nil(t, os.MkdirAll(nested, 0700))
+
+	path, err := config.Find(nested)
+	require.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, config.Filename), path)
+
+	cfg, err := config.Load(path)
+	require.Nil(t, err)
+
+	assert.Equal(t, dir, cfg.Dir())
+	assert.Equal(t, map[string]string{
+		"server":           "https://plantuml.example.com",
+		"format":           "png",
+		"quiet":            "true",
+		"ca-cert":          filepath.Join(dir, "certs", "ca.pem"),
+		"timeout":          "30s",
+		"max-participants": "8",
+		"skin":             filepath.Join(dir, "skin.iuml"),
+		"auto-stage":       "true",
+		"skip":             "lint,build",
+	}, cfg.Defaults())
+
+	assert.Equal(t, map[string]bool{"hardcoded-color": false}, cfg.Lint.Rules)
+
+	target, err := cfg.Target("docs")
+	require.Nil(t, err)
+	assert.Equal(t, config.Target{Inputs: []string{"docs/**/*.puml"}, Formats: []string{"svg", "png"}, OutDir: "docs/img"}, target)
+
+	_, err = cfg.Target("missing")
+	assert.EqualError(t, err, "unknown target: [missing], the targets in "+path+" are: [docs]")
+
+	path, err = config.Find(t.TempDir())
+	require.Nil(t, err)
+	assert.Empty(t, path)
+}
+
+func Test_LoadWithUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), config.Filename)
+	writeFile(t, path, "sever: https://plantuml.example.com\n")
+
+	_, err := config.Load(path)
+	assert.NotNil(t, err)
+}
+
+func Test_EnvName(t *testing.T) {
+	assert.Equal(t, "GOPUML_SERVER", config.EnvName("server"))
+	assert.Equal(t, "GOPUML_ERROR_FORMAT", config.EnvName("error-format"))
+}
+
+func Test_Glob(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, path := range []string{"a.puml", "docs/b.puml", "docs/nested/c.puml", "docs/nested/d.txt"} {
+		writeFile(t, filepath.Join(dir, path), "")
+	}
+
+	actual, err := config.Glob(dir, []string{"docs/**/*.puml"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "docs/b.puml"), filepath.Join(dir, "docs/nested/c.puml")}, actual)
+
+	actual, err = config.Glob(dir, []string{"*.puml", "a.puml"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.puml")}, actual)
+
+	assert.Equal(t, filepath.Join(dir, "docs"), config.GlobRoot(dir, "docs/**/*.puml"))
+	assert.Equal(t, filepath.Join(dir, "docs"), config.GlobRoot(dir, "docs/b.puml"))
+	assert.Equal(t, dir, config.GlobRoot(dir, "*.puml"))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+}
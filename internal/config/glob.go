@@ -0,0 +1,99 @@
+package config
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Glob returns the files matching the patterns, relative to the directory.
+// The patterns use the syntax of filepath.Match, where a ** matches any number of directories.
+func Glob(dir string, patterns []string) ([]string, error) {
+	matches := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		root := walkRoot(pattern)
+		patternSegments := strings.Split(filepath.ToSlash(pattern), "/")
+
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !entry.IsDir() && matchSegments(patternSegments, strings.Split(filepath.ToSlash(path), "/")) {
+				matches[path] = true
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]string, 0, len(matches))
+
+	for path := range matches {
+		result = append(result, path)
+	}
+
+	sort.Strings(result)
+
+	return result, nil
+}
+
+// GlobRoot returns the directory of the pattern before the first segment with a wildcard, relative to the directory.
+// The directory of the pattern is returned if it has no wildcard.
+func GlobRoot(dir, pattern string) string {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if root := walkRoot(pattern); root != pattern {
+		return root
+	}
+
+	return filepath.Dir(pattern)
+}
+
+// walkRoot returns the directory of the pattern before the first segment with a wildcard.
+func walkRoot(pattern string) string {
+	root := pattern
+
+	for strings.ContainsAny(root, "*?[") {
+		root = filepath.Dir(root)
+	}
+
+	return root
+}
+
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for idx := 0; idx <= len(path); idx++ {
+			if matchSegments(pattern[1:], path[idx:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	if ok, err := filepath.Match(pattern[0], path[0]); err != nil || !ok {
+		return false
+	}
+
+	return matchSegments(pattern[1:], path[1:])
+}
@@ -0,0 +1,66 @@
+// Package diagnostic reports problems found in Plant UML files.
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Diagnostic is a problem found on a line in a file.
+type Diagnostic struct {
+	File string `json:"file"`
+	// Line is the line in the file, starting at 1, and 0 when unknown.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+	// Rule is the rule which found the problem, if any.
+	Rule string `json:"rule,omitempty"`
+}
+
+// String formats the diagnostic as "<file>:<line>: <message>".
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location = fmt.Sprintf("%s:%d", d.File, d.Line)
+	}
+
+	if d.Rule != "" {
+		return fmt.Sprintf("%s: %s (%s)", location, d.Message, d.Rule)
+	}
+
+	return fmt.Sprintf("%s: %s", location, d.Message)
+}
+
+const (
+	// FormatText writes one diagnostic per line.
+	FormatText = "text"
+	// FormatJSON writes the diagnostics as a JSON array.
+	FormatJSON = "json"
+)
+
+// Write writes the diagnostics to out in the given format.
+func Write(out io.Writer, format string, diagnostics []Diagnostic) error {
+	switch format {
+	case FormatText:
+		for _, d := range diagnostics {
+			if _, err := fmt.Fprintln(out, d.String()); err != nil {
+				return fmt.Errorf("couldn't write diagnostic: %w", err)
+			}
+		}
+	case FormatJSON:
+		if diagnostics == nil {
+			diagnostics = []Diagnostic{}
+		}
+
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(diagnostics); err != nil {
+			return fmt.Errorf("couldn't write diagnostics: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported diagnostic format: [%s]", format)
+	}
+
+	return nil
+}
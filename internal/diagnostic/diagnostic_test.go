@@ -0,0 +1,55 @@
+package diagnostic_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+)
+
+func Test_Write(t *testing.T) {
+	diagnostics := []diagnostic.Diagnostic{
+		{File: "diagrams/auth.puml", Line: 12, Message: "Syntax Error?"},
+		{File: "diagrams/user.puml", Message: "missing @enduml", Rule: "enduml"},
+	}
+
+	testcases := []struct {
+		format         string
+		expectedOutput string
+	}{
+		{
+			format:         diagnostic.FormatText,
+			expectedOutput: "diagrams/auth.puml:12: Syntax Error?\ndiagrams/user.puml: missing @enduml (enduml)\n",
+		},
+		{
+			format: diagnostic.FormatJSON,
+			expectedOutput: `[
+  {
+    "file": "diagrams/auth.puml",
+    "line": 12,
+    "message": "Syntax Error?"
+  },
+  {
+    "file": "diagrams/user.puml",
+    "message": "missing @enduml",
+    "rule": "enduml"
+  }
+]
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		var out bytes.Buffer
+
+		err := diagnostic.Write(&out, tc.format, diagnostics)
+		require.Nil(t, err)
+		assert.Equal(t, tc.expectedOutput, out.String())
+	}
+
+	err := diagnostic.Write(&bytes.Buffer{}, "xml", diagnostics)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,64 @@
+package docs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lonnblad/gopuml"
+)
+
+const hexPrefix = "~h"
+
+// DecodeLink decodes the diagram in the link into its Plant UML source.
+// Both the default encoding and the hex encoding, prefixed with "~h", are supported.
+func DecodeLink(link Link) ([]byte, error) {
+	if strings.HasPrefix(link.Encoded, hexPrefix) {
+		content, err := hex.DecodeString(strings.TrimPrefix(link.Encoded, hexPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't decode hex encoded diagram: %w", err)
+		}
+
+		return content, nil
+	}
+
+	if strings.HasPrefix(link.Encoded, "~") {
+		return nil, fmt.Errorf("unsupported encoding: %s", link.Encoded[:minInt(2, len(link.Encoded))]) // nolint: gomnd
+	}
+
+	compressed, err := gopuml.Decode([]byte(link.Encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	return gopuml.Inflate(compressed)
+}
+
+// FormatMarker formats a marker for the source, as a line comment in AsciiDoc documents
+// and as an HTML comment in other documents.
+func FormatMarker(ext, src string) string {
+	if strings.ContainsAny(src, " \t") {
+		src = `"` + src + `"`
+	}
+
+	switch strings.ToLower(ext) {
+	case ".adoc", ".asciidoc":
+		return "// gopuml:src=" + src
+	default:
+		return "<!-- gopuml:src=" + src + " -->"
+	}
+}
+
+// IsMarked returns true if the line is preceded by a marker,
+// which is the first line before the line which isn't blank.
+func IsMarked(lines []string, line int) bool {
+	for idx := line - 1; idx >= 0; idx-- {
+		if strings.TrimSpace(lines[idx]) == "" {
+			continue
+		}
+
+		return markerRegexp.MatchString(lines[idx])
+	}
+
+	return false
+}
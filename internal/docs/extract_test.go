@@ -0,0 +1,47 @@
+package docs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/internal/docs"
+)
+
+func Test_DecodeLink(t *testing.T) {
+	content := []byte("@startuml\nBob -> Alice : hello\n@enduml")
+
+	compressed, err := gopuml.Deflate(content)
+	require.Nil(t, err)
+
+	link := docs.Link{Server: "https://srv", Format: "svg", Encoded: string(gopuml.Encode(compressed))}
+
+	actual, err := docs.DecodeLink(link)
+	require.Nil(t, err)
+	assert.Equal(t, content, actual)
+
+	link.Encoded = "~h" + "4040"
+	actual, err = docs.DecodeLink(link)
+	require.Nil(t, err)
+	assert.Equal(t, []byte("@@"), actual)
+
+	link.Encoded = "~1abcd"
+	_, err = docs.DecodeLink(link)
+	assert.NotNil(t, err)
+}
+
+func Test_FormatMarker(t *testing.T) {
+	assert.Equal(t, "<!-- gopuml:src=a.puml -->", docs.FormatMarker(".md", "a.puml"))
+	assert.Equal(t, "// gopuml:src=a.puml", docs.FormatMarker(".adoc", "a.puml"))
+	assert.Equal(t, `<!-- gopuml:src="a b.puml" -->`, docs.FormatMarker(".html", "a b.puml"))
+}
+
+func Test_IsMarked(t *testing.T) {
+	lines := []string{"<!-- gopuml:src=a.puml -->", "", "![a](https://srv/svg/x)", "![b](https://srv/svg/y)"}
+
+	assert.True(t, docs.IsMarked(lines, 2))
+	assert.False(t, docs.IsMarked(lines, 3))
+	assert.False(t, docs.IsMarked(lines, 0))
+}
@@ -0,0 +1,97 @@
+package docs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Marker is a comment which marks the link on the following line as rendered from a source file,
+// like:
+//
+//	<!-- gopuml:src=diagrams/auth.puml format=svg -->
+//	// gopuml:src=diagrams/auth.puml format=svg
+//
+// The second form is used in AsciiDoc, the format is optional.
+type Marker struct {
+	// Line is the index of the line with the marker.
+	Line int
+	// Src is the path to the source file, relative to the document.
+	Src string
+	// Format is the format to link to, it's empty when the format of the link should be kept.
+	Format string
+}
+
+var (
+	markerRegexp = regexp.MustCompile(`^\s*(?:<!--|//)\s*gopuml:src=("[^"]+"|\S+)(?:\s+format=(\w+))?\s*(?:-->)?\s*$`)
+	linkRegexp   = regexp.MustCompile(`(https?://[^\s()<>"'\[\]]+?)/(png|svg|txt)/(~?[0-9A-Za-z\-_]+)`)
+)
+
+// FindMarkers finds all markers in the lines of a document.
+func FindMarkers(lines []string) []Marker {
+	var markers []Marker
+
+	for idx, line := range lines {
+		if matches := markerRegexp.FindStringSubmatch(line); matches != nil {
+			markers = append(markers, Marker{Line: idx, Src: strings.Trim(matches[1], `"`), Format: matches[2]})
+		}
+	}
+
+	return markers
+}
+
+// Link is a link to a diagram rendered by a Plant UML server.
+type Link struct {
+	Server  string
+	Format  string
+	Encoded string
+}
+
+// String formats the link as "<server>/<format>/<encoded>".
+func (l Link) String() string {
+	return l.Server + "/" + l.Format + "/" + l.Encoded
+}
+
+// FindLinks finds all links to diagrams rendered by a Plant UML server in the line.
+func FindLinks(line string) []Link {
+	var links []Link
+
+	for _, matches := range linkRegexp.FindAllStringSubmatch(line, -1) {
+		links = append(links, Link{Server: matches[1], Format: matches[2], Encoded: matches[3]})
+	}
+
+	return links
+}
+
+// LinkedLine returns the index of the line with the link following the marker,
+// which is the first line after the marker which isn't blank.
+// It returns -1 if no such line has a link.
+func LinkedLine(lines []string, marker Marker) int {
+	for idx := marker.Line + 1; idx < len(lines); idx++ {
+		if strings.TrimSpace(lines[idx]) == "" {
+			continue
+		}
+
+		if len(FindLinks(lines[idx])) == 0 {
+			return -1
+		}
+
+		return idx
+	}
+
+	return -1
+}
+
+// ReplaceLinks replaces the links in the line with links to the encoded diagram,
+// the server of the current links is kept, and the format if the given format is empty.
+func ReplaceLinks(line, format, encoded string) string {
+	return linkRegexp.ReplaceAllStringFunc(line, func(match string) string {
+		link := FindLinks(match)[0]
+		link.Encoded = encoded
+
+		if format != "" {
+			link.Format = format
+		}
+
+		return link.String()
+	})
+}
@@ -0,0 +1,53 @@
+package docs_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/docs"
+)
+
+func Test_FindMarkers(t *testing.T) {
+	lines := docs.SplitLines(`# Title
+<!-- gopuml:src=diagrams/auth.puml format=svg -->
+![auth](https://www.plantuml.com/plantuml/svg/SYWkIImgAStDKN2jICmjo4dbSifFKj2rKt3CoKnELR1Io4ZDoSddSaZDIodDpG44003__m00)
+
+// gopuml:src="diagrams/user flow.puml"
+
+image::https://plantuml.example.com/plantuml/png/~1abc[]
+<!-- gopuml:src=missing.puml -->
+No link here.`)
+
+	markers := docs.FindMarkers(lines)
+
+	expected := []docs.Marker{
+		{Line: 1, Src: "diagrams/auth.puml", Format: "svg"},
+		{Line: 4, Src: "diagrams/user flow.puml"},
+		{Line: 7, Src: "missing.puml"},
+	}
+	require.Equal(t, expected, markers)
+
+	assert.Equal(t, 2, docs.LinkedLine(lines, markers[0]))
+	assert.Equal(t, 6, docs.LinkedLine(lines, markers[1]))
+	assert.Equal(t, -1, docs.LinkedLine(lines, markers[2]))
+
+	assert.Equal(t,
+		[]docs.Link{{Server: "https://plantuml.example.com/plantuml", Format: "png", Encoded: "~1abc"}},
+		docs.FindLinks(lines[6]),
+	)
+}
+
+func Test_ReplaceLinks(t *testing.T) {
+	line := "[![a](https://srv/plantuml/svg/old)](https://srv/plantuml/txt/old)"
+
+	assert.Equal(t,
+		"[![a](https://srv/plantuml/png/new)](https://srv/plantuml/png/new)",
+		docs.ReplaceLinks(line, "png", "new"),
+	)
+	assert.Equal(t,
+		"[![a](https://srv/plantuml/svg/new)](https://srv/plantuml/txt/new)",
+		docs.ReplaceLinks(line, "", "new"),
+	)
+}
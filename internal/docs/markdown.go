@@ -0,0 +1,127 @@
+// Package docs finds and updates Plant UML diagrams embedded in documents.
+package docs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// ReferenceBegin and ReferenceEnd mark the reference to the rendered diagram inserted after a code fence.
+	ReferenceBegin = "<!-- gopuml:begin -->"
+	ReferenceEnd   = "<!-- gopuml:end -->"
+)
+
+// Fence is a plantuml or puml code fence in a Markdown document.
+type Fence struct {
+	// Start and End are the indexes of the lines opening and closing the fence.
+	Start, End int
+	// Content is the content of the fence.
+	Content string
+}
+
+var fenceRegexp = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*(\\S*)")
+
+var fenceLanguages = map[string]bool{"plantuml": true, "puml": true}
+
+// SplitLines splits the content into lines without line endings.
+func SplitLines(content string) []string {
+	return strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+}
+
+// FindFences finds all plantuml and puml code fences in the lines of a Markdown document.
+// An unclosed fence ends at the end of the document.
+func FindFences(lines []string) []Fence {
+	var fences []Fence
+
+	for idx := 0; idx < len(lines); idx++ {
+		matches := fenceRegexp.FindStringSubmatch(lines[idx])
+		if matches == nil {
+			continue
+		}
+
+		marker := matches[1]
+		language := strings.ToLower(strings.Trim(matches[2], "{}."))
+
+		end := len(lines)
+
+		for next := idx + 1; next < len(lines); next++ {
+			trimmed := strings.TrimSpace(lines[next])
+			if strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, marker[:1]) == "" {
+				end = next
+				break
+			}
+		}
+
+		if fenceLanguages[language] {
+			fences = append(fences, Fence{
+				Start:   idx,
+				End:     end,
+				Content: strings.Join(lines[idx+1:minInt(end, len(lines))], "\n") + "\n",
+			})
+		}
+
+		idx = end
+	}
+
+	return fences
+}
+
+// InsertReferences inserts a reference block after every fence,
+// an existing reference block directly after a fence is replaced.
+// The references are the lines to put in the block of every fence.
+// An error is returned if a reference block isn't ended before the next fence or the end of the document.
+func InsertReferences(lines []string, fences []Fence, references []string) ([]string, error) {
+	result := make([]string, 0, len(lines)+len(fences)*3) // nolint: gomnd
+
+	next := 0
+
+	for idx, fence := range fences {
+		end := minInt(fence.End, len(lines)-1)
+		result = append(result, lines[next:end+1]...)
+		next = end + 1
+
+		if next < len(lines) && strings.TrimSpace(lines[next]) == ReferenceBegin {
+			blockEnd, ok := findReferenceEnd(lines, next, fences[idx+1:])
+			if !ok {
+				return nil, fmt.Errorf("line %d: the reference block isn't ended with %s", next+1, ReferenceEnd)
+			}
+
+			next = blockEnd + 1
+		}
+
+		result = append(result, ReferenceBegin, references[idx], ReferenceEnd)
+	}
+
+	if next < len(lines) {
+		result = append(result, lines[next:]...)
+	}
+
+	return result, nil
+}
+
+// findReferenceEnd returns the index of the line ending the reference block beginning on the line,
+// the block must end before the next of the fences.
+func findReferenceEnd(lines []string, begin int, fences []Fence) (int, bool) {
+	limit := len(lines)
+	if len(fences) > 0 {
+		limit = minInt(fences[0].Start, limit)
+	}
+
+	for idx := begin + 1; idx < limit; idx++ {
+		if strings.TrimSpace(lines[idx]) == ReferenceEnd {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
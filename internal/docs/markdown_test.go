@@ -0,0 +1,115 @@
+package docs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/docs"
+)
+
+const markdown = "# Title\n" +
+	"\n" +
+	"```plantuml\n" +
+	"@startuml Example\n" +
+	"Bob -> Alice : hello\n" +
+	"@enduml\n" +
+	"```\n" +
+	"\n" +
+	"```go\n" +
+	"fmt.Println(\"```puml\")\n" +
+	"```\n" +
+	"\n" +
+	"~~~~ puml\n" +
+	"@startuml\n" +
+	"~~~~\n" +
+	"<!-- gopuml:begin -->\n" +
+	"![old](old.svg)\n" +
+	"<!-- gopuml:end -->\n" +
+	"The end."
+
+func Test_FindFences(t *testing.T) {
+	fences := docs.FindFences(docs.SplitLines(markdown))
+
+	expected := []docs.Fence{
+		{Start: 2, End: 6, Content: "@startuml Example\nBob -> Alice : hello\n@enduml\n"},
+		{Start: 12, End: 14, Content: "@startuml\n"},
+	}
+
+	assert.Equal(t, expected, fences)
+}
+
+func Test_InsertReferences(t *testing.T) {
+	lines := docs.SplitLines(markdown)
+	fences := docs.FindFences(lines)
+	require.Len(t, fences, 2)
+
+	references := []string{"![Example](example.svg)", "![doc-2](doc-2.svg)"}
+
+	inserted, err := docs.InsertReferences(lines, fences, references)
+	require.Nil(t, err)
+
+	actual := strings.Join(inserted, "\n")
+
+	expected := "# Title\n" +
+		"\n" +
+		"```plantuml\n" +
+		"@startuml Example\n" +
+		"Bob -> Alice : hello\n" +
+		"@enduml\n" +
+		"```\n" +
+		"<!-- gopuml:begin -->\n" +
+		"![Example](example.svg)\n" +
+		"<!-- gopuml:end -->\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"```puml\")\n" +
+		"```\n" +
+		"\n" +
+		"~~~~ puml\n" +
+		"@startuml\n" +
+		"~~~~\n" +
+		"<!-- gopuml:begin -->\n" +
+		"![doc-2](doc-2.svg)\n" +
+		"<!-- gopuml:end -->\n" +
+		"The end."
+
+	assert.Equal(t, expected, actual)
+
+	lines = docs.SplitLines(actual)
+	inserted, err = docs.InsertReferences(lines, docs.FindFences(lines), references)
+	require.Nil(t, err)
+	assert.Equal(t, expected, strings.Join(inserted, "\n"))
+}
+
+func Test_InsertReferencesWithoutEnd(t *testing.T) {
+	content := "```plantuml\n" +
+		"@startuml\n" +
+		"```\n" +
+		"<!-- gopuml:begin -->\n" +
+		"![doc-1](doc-1.svg)\n" +
+		"\n" +
+		"```plantuml\n" +
+		"@startuml\n" +
+		"```\n" +
+		"<!-- gopuml:begin -->\n" +
+		"![doc-2](doc-2.svg)\n" +
+		"<!-- gopuml:end -->\n" +
+		"The end."
+
+	lines := docs.SplitLines(content)
+
+	_, err := docs.InsertReferences(lines, docs.FindFences(lines), []string{"![doc-1](doc-1.svg)", "![doc-2](doc-2.svg)"})
+	require.NotNil(t, err)
+	assert.Equal(t, "line 4: the reference block isn't ended with <!-- gopuml:end -->", err.Error())
+
+	// The last reference block would otherwise consume the rest of the document.
+	lines = lines[:len(lines)-2]
+	lines[4] = "<!-- gopuml:end -->"
+
+	_, err = docs.InsertReferences(lines, docs.FindFences(lines), []string{"![doc-1](doc-1.svg)", "![doc-2](doc-2.svg)"})
+	require.NotNil(t, err)
+	assert.Equal(t, "line 10: the reference block isn't ended with <!-- gopuml:end -->", err.Error())
+}
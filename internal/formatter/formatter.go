@@ -0,0 +1,120 @@
+// Package formatter formats Plant UML source in a canonical style.
+//
+// The formatter indents lines by their nesting depth, normalizes the spacing
+// around arrows and colons in messages, lower cases directives and keywords,
+// collapses consecutive blank lines and removes trailing whitespace.
+// Comments and free form text, like the text in notes, are kept as is.
+package formatter
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/lonnblad/gopuml/internal/parser"
+)
+
+// Indent is the indentation used for every nesting level.
+const Indent = "  "
+
+var (
+	directiveRegexp = regexp.MustCompile(`^[@!]\w+`)
+	keywordRegexp   = regexp.MustCompile(
+		`(?i)^(?:alt|opt|loop|par|break|critical|group|box|else\s+if|elseif|else|end\s+\w+|end\w*|` +
+			`if|while|repeat\s+while|repeat|fork\s+again|fork|split\s+again|split|switch|case|` +
+			`[hr]?note|ref|legend|title|header|footer|caption|skinparam)\b`,
+	)
+	spacesRegexp = regexp.MustCompile(`\s+`)
+)
+
+// Format formats the content.
+func Format(content []byte) []byte {
+	var buffer bytes.Buffer
+
+	previousBlank := true
+
+	for _, line := range parser.Parse(content) {
+		if line.Kind == parser.KindBlank {
+			if !previousBlank {
+				buffer.WriteByte('\n')
+			}
+
+			previousBlank = true
+
+			continue
+		}
+
+		previousBlank = false
+
+		buffer.WriteString(formatLine(line))
+		buffer.WriteByte('\n')
+	}
+
+	formatted := bytes.TrimRight(buffer.Bytes(), "\n")
+	if len(formatted) == 0 {
+		return formatted
+	}
+
+	return append(formatted, '\n')
+}
+
+func formatLine(line parser.Line) string {
+	switch line.Kind {
+	case parser.KindComment, parser.KindText:
+		return line.Text
+	case parser.KindMessage:
+		return indent(line.Depth) + formatMessage(line.Message)
+	case parser.KindStart, parser.KindEnd:
+		return lowerDirective(line.Content())
+	case parser.KindBlank:
+		return ""
+	case parser.KindOpen, parser.KindElse, parser.KindClose:
+		// A line which opens a block with a brace starts with a name, like "Endpoint {", not a keyword.
+		if line.Brace {
+			return indent(line.Depth) + line.Content()
+		}
+
+		return indent(line.Depth) + lowerKeyword(line.Content())
+	case parser.KindStatement:
+	}
+
+	content := line.Content()
+	if strings.HasPrefix(content, "!") {
+		content = lowerDirective(content)
+	} else if strings.HasPrefix(strings.ToLower(content), "skinparam") {
+		content = lowerKeyword(content)
+	}
+
+	return indent(line.Depth) + content
+}
+
+func indent(depth int) string {
+	return strings.Repeat(Indent, depth)
+}
+
+func formatMessage(message *parser.Message) string {
+	formatted := message.From + " " + message.Arrow + " " + message.To
+
+	if message.HasLabel {
+		formatted += " :"
+
+		if message.Label != "" {
+			formatted += " " + message.Label
+		}
+	}
+
+	return formatted
+}
+
+// lowerDirective lower cases a leading directive, like @StartUML or !INCLUDE.
+func lowerDirective(content string) string {
+	return directiveRegexp.ReplaceAllStringFunc(content, strings.ToLower)
+}
+
+// lowerKeyword lower cases a leading keyword, like ALT or End Note,
+// and collapses the whitespace in keywords of several words.
+func lowerKeyword(content string) string {
+	return keywordRegexp.ReplaceAllStringFunc(content, func(keyword string) string {
+		return spacesRegexp.ReplaceAllString(strings.ToLower(keyword), " ")
+	})
+}
@@ -0,0 +1,179 @@
+package formatter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lonnblad/gopuml/internal/formatter"
+)
+
+func Test_Format(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "formatted",
+			input:    "@startuml Example\nBob -> Alice : hello\n@enduml\n",
+			expected: "@startuml Example\nBob -> Alice : hello\n@enduml\n",
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: "",
+		},
+		{
+			name:     "arrows and colons",
+			input:    "@StartUML Example\nBob->Alice:hello\nAlice  -->>  Bob :   hi  \n[-> Bob\nBob ->x Alice:\n@EndUML",
+			expected: "@startuml Example\nBob -> Alice : hello\nAlice -->> Bob : hi\n[ -> Bob\nBob ->x Alice :\n@enduml\n",
+		},
+		{
+			name:     "capitalised names before braces",
+			input:    "@startuml Names\nEndpoint {\nAltitude {\nclass Repeater {\n}\n}\n}\n@enduml\n",
+			expected: "@startuml Names\nEndpoint {\n  Altitude {\n    class Repeater {\n    }\n  }\n}\n@enduml\n",
+		},
+		{
+			name: "blocks",
+			input: `@startuml Blocks
+ALT success
+Bob -> Alice : hello
+      else failure
+    group retry
+  loop 3 times
+Bob -> Alice : hello
+End
+ end
+    END
+box "Internal" #LightBlue
+participant Bob
+End Box
+@enduml`,
+			expected: `@startuml Blocks
+alt success
+  Bob -> Alice : hello
+else failure
+  group retry
+    loop 3 times
+      Bob -> Alice : hello
+    end
+  end
+end
+box "Internal" #LightBlue
+  participant Bob
+end box
+@enduml
+`,
+		},
+		{
+			name: "notes and comments",
+			input: `@startuml Notes
+alt
+   ' a comment
+note left of Bob
+     free   form
+text
+end note
+/'
+   block
+'/
+note right : single line
+end
+@enduml`,
+			expected: `@startuml Notes
+alt
+   ' a comment
+  note left of Bob
+     free   form
+text
+  end note
+/'
+   block
+'/
+  note right : single line
+end
+@enduml
+`,
+		},
+		{
+			name: "activity",
+			input: `@startuml Activity
+start
+IF (ok?) then (yes)
+:multi
+  line;
+elseif (maybe?) then (yes)
+while (more?)
+:work;
+endwhile
+else (no)
+fork
+:a;
+fork again
+:b;
+end fork
+endif
+end
+@enduml`,
+			expected: `@startuml Activity
+start
+if (ok?) then (yes)
+  :multi
+  line;
+elseif (maybe?) then (yes)
+  while (more?)
+    :work;
+  endwhile
+else (no)
+  fork
+    :a;
+  fork again
+    :b;
+  end fork
+endif
+end
+@enduml
+`,
+		},
+		{
+			name: "directives, braces and blank lines",
+			input: `
+
+@startuml Classes
+!INCLUDE skin.puml
+SkinParam monochrome true
+
+
+package Foo {
+class Bar {
++ name : string
+}
+}   
+A "1" *-- "many" B : contains
+
+
+@enduml
+
+`,
+			expected: `@startuml Classes
+!include skin.puml
+skinparam monochrome true
+
+package Foo {
+  class Bar {
+    + name : string
+  }
+}
+A "1" *-- "many" B : contains
+
+@enduml
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		actual := formatter.Format([]byte(tc.input))
+		assert.Equal(t, tc.expected, string(actual), tc.name)
+	}
+}
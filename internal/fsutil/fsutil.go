@@ -0,0 +1,67 @@
+// Package fsutil writes files for commands which are run often, like from go:generate directives.
+package fsutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes the content to the file at the path, if the file doesn't already have the content.
+// It returns true if the file was written.
+//
+// The content is written to a temporary file in the same directory, which is then renamed to the path,
+// so the file is never left partially written. The permissions are used when the file is created,
+// the permissions of an existing file are kept.
+func WriteFile(path string, content []byte, perm fs.FileMode) (_ bool, err error) {
+	info, err := os.Stat(path)
+
+	switch {
+	case err == nil:
+		perm = info.Mode().Perm()
+
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+
+		if bytes.Equal(existing, content) {
+			return false, nil
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return false, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return false, fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name()) // nolint: errcheck
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close() // nolint: errcheck
+		return false, fmt.Errorf("couldn't write temporary file: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return false, fmt.Errorf("couldn't close temporary file: %w", err)
+	}
+
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return false, fmt.Errorf("couldn't set permissions: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return false, fmt.Errorf("couldn't rename temporary file: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,56 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/fsutil"
+)
+
+func Test_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "example.svg")
+
+	written, err := fsutil.WriteFile(path, []byte("a longer first version"), 0640)
+	require.Nil(t, err)
+	assert.True(t, written)
+
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	past := time.Now().Add(-time.Hour)
+	require.Nil(t, os.Chtimes(path, past, past))
+
+	written, err = fsutil.WriteFile(path, []byte("a longer first version"), 0600)
+	require.Nil(t, err)
+	assert.False(t, written)
+
+	info, err = os.Stat(path)
+	require.Nil(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+
+	written, err = fsutil.WriteFile(path, []byte("shorter"), 0600)
+	require.Nil(t, err)
+	assert.True(t, written)
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, "shorter", string(content))
+
+	info, err = os.Stat(path)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = fsutil.WriteFile(filepath.Join(dir, "missing", "example.svg"), []byte("x"), 0600)
+	assert.NotNil(t, err)
+}
@@ -2,7 +2,10 @@ package generator
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,13 +18,23 @@ type File struct {
 	UpdatedAt time.Time
 	Raw       []byte
 	Encoded   []byte
+
+	// Err is the error returned by the Validator for the current content.
+	Err error
+	// LastValidEncoded is the encoded content of the latest version
+	// which passed the Validator, it's empty if no version has passed.
+	LastValidEncoded []byte
 }
 
+// Validator validates encoded content, for example by rendering it.
+type Validator func(ctx context.Context, encoded []byte) error
+
 type Generator struct {
 	files map[string]File
 	subs  map[int]chan File
 
 	noOfSubs int
+	validate Validator
 
 	mutex sync.RWMutex
 }
@@ -33,11 +46,22 @@ func New() *Generator {
 	}
 }
 
-func (gen *Generator) PutFile(path string, rawContent []byte) error {
+// SetValidator sets the Validator used to validate the content of new and modified files.
+func (gen *Generator) SetValidator(validate Validator) {
 	gen.mutex.Lock()
 	defer gen.mutex.Unlock()
 
+	gen.validate = validate
+}
+
+// PutFile adds or updates the file, the context is used to validate the content.
+// When the context is done while validating, the file isn't updated.
+func (gen *Generator) PutFile(ctx context.Context, path string, rawContent []byte) error {
+	gen.mutex.RLock()
 	oldFile := gen.files[path]
+	validate := gen.validate
+	gen.mutex.RUnlock()
+
 	if oldFile.Filepath == path && bytes.Equal(oldFile.Raw, rawContent) {
 		return nil
 	}
@@ -55,12 +79,37 @@ func (gen *Generator) PutFile(path string, rawContent []byte) error {
 		UpdatedAt: time.Now(),
 		Raw:       rawContent,
 		Encoded:   encoded,
+
+		LastValidEncoded: oldFile.LastValidEncoded,
 	}
 
+	// The validation is done without holding the lock,
+	// since it can be slow when the content is rendered.
+	if validate != nil {
+		f.Err = validate(ctx, encoded)
+
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	if f.Err == nil {
+		f.LastValidEncoded = encoded
+	}
+
+	gen.mutex.Lock()
+	defer gen.mutex.Unlock()
+
 	gen.files[path] = f
 
 	for _, sub := range gen.subs {
-		sub <- f
+		// Subscribers are only notified that a file has changed,
+		// a subscriber which hasn't consumed the previous notification
+		// is expected to re-read the files when it does.
+		select {
+		case sub <- f:
+		default:
+		}
 	}
 
 	return nil
@@ -72,7 +121,7 @@ func (gen *Generator) RegisterSub() (int, chan File) {
 
 	gen.noOfSubs++
 	id := gen.noOfSubs
-	gen.subs[id] = make(chan File)
+	gen.subs[id] = make(chan File, 1)
 
 	return id, gen.subs[id]
 }
@@ -84,7 +133,23 @@ func (gen *Generator) DeregisterSub(id int) {
 	delete(gen.subs, id)
 }
 
+// GetFile returns the file with the given path.
+func (gen *Generator) GetFile(path string) (File, bool) {
+	gen.mutex.RLock()
+	defer gen.mutex.RUnlock()
+
+	f, ok := gen.files[path]
+
+	return f, ok
+}
+
+// GetFiles returns all files sorted by their path.
 func (gen *Generator) GetFiles() []File {
+	return gen.GetSortedFiles(OrderPath)
+}
+
+// GetSortedFiles returns all files sorted in the given order.
+func (gen *Generator) GetSortedFiles(order Order) []File {
 	gen.mutex.RLock()
 	defer gen.mutex.RUnlock()
 
@@ -94,5 +159,54 @@ func (gen *Generator) GetFiles() []File {
 		result = append(result, file)
 	}
 
+	SortFiles(result, order)
+
 	return result
 }
+
+// Order is the order in which files are sorted.
+type Order string
+
+const (
+	// OrderPath sorts the files by their full path.
+	OrderPath Order = "path"
+	// OrderName sorts the files by their filename.
+	OrderName Order = "name"
+	// OrderModified sorts the files with the latest updated file first.
+	OrderModified Order = "modified"
+)
+
+// Orders lists the supported orders.
+var Orders = []Order{OrderPath, OrderName, OrderModified}
+
+// ParseOrder parses the order, returns an error if the order isn't supported.
+func ParseOrder(order string) (Order, error) {
+	for _, o := range Orders {
+		if string(o) == order {
+			return o, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported order: [%s]", order)
+}
+
+// SortFiles sorts the files in the given order, ties are sorted by path.
+func SortFiles(files []File, order Order) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+
+		switch order {
+		case OrderName:
+			if a.Filename != b.Filename {
+				return a.Filename < b.Filename
+			}
+		case OrderModified:
+			if !a.UpdatedAt.Equal(b.UpdatedAt) {
+				return a.UpdatedAt.After(b.UpdatedAt)
+			}
+		case OrderPath:
+		}
+
+		return a.Filepath < b.Filepath
+	})
+}
@@ -1,12 +1,16 @@
 package generator_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/lonnblad/gopuml"
 	"github.com/lonnblad/gopuml/example"
 	"github.com/lonnblad/gopuml/internal/generator"
 )
@@ -42,7 +46,7 @@ func Test_Generator(t *testing.T) {
 		}
 	}()
 
-	err := gen.PutFile(expectedFile.Filepath, expectedFile.Raw)
+	err := gen.PutFile(context.Background(), expectedFile.Filepath, expectedFile.Raw)
 	require.Nil(t, err)
 
 	fs := gen.GetFiles()
@@ -51,7 +55,14 @@ func Test_Generator(t *testing.T) {
 	actualFile := fs[0]
 	equalFile(t, expectedFile, actualFile)
 
-	err = gen.PutFile(expectedFile.Filepath, expectedFile.Raw)
+	actualFile, ok := gen.GetFile(expectedFile.Filepath)
+	require.True(t, ok)
+	equalFile(t, expectedFile, actualFile)
+
+	_, ok = gen.GetFile("<path>/missing.puml")
+	assert.False(t, ok)
+
+	err = gen.PutFile(context.Background(), expectedFile.Filepath, expectedFile.Raw)
 	require.Nil(t, err)
 
 	for n := 1; n <= 3; n++ {
@@ -76,3 +87,100 @@ func equalFile(t *testing.T, expected, actual generator.File) {
 	assert.Equal(t, expected.Raw, actual.Raw)
 	assert.Equal(t, expected.Encoded, actual.Encoded)
 }
+
+func Test_SortFiles(t *testing.T) {
+	now := time.Now()
+
+	files := []generator.File{
+		{Filepath: "/b/a.puml", Filename: "a.puml", UpdatedAt: now.Add(-time.Minute)},
+		{Filepath: "/a/c.puml", Filename: "c.puml", UpdatedAt: now},
+		{Filepath: "/a/b.puml", Filename: "b.puml", UpdatedAt: now.Add(-time.Hour)},
+	}
+
+	testcases := []struct {
+		order         generator.Order
+		expectedPaths []string
+	}{
+		{order: generator.OrderPath, expectedPaths: []string{"/a/b.puml", "/a/c.puml", "/b/a.puml"}},
+		{order: generator.OrderName, expectedPaths: []string{"/b/a.puml", "/a/b.puml", "/a/c.puml"}},
+		{order: generator.OrderModified, expectedPaths: []string{"/a/c.puml", "/b/a.puml", "/a/b.puml"}},
+	}
+
+	for _, tc := range testcases {
+		generator.SortFiles(files, tc.order)
+
+		actualPaths := make([]string, len(files))
+		for idx, f := range files {
+			actualPaths[idx] = f.Filepath
+		}
+
+		assert.Equal(t, tc.expectedPaths, actualPaths, tc.order)
+	}
+}
+
+func Test_ParseOrder(t *testing.T) {
+	for _, expected := range generator.Orders {
+		actual, err := generator.ParseOrder(string(expected))
+		require.Nil(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err := generator.ParseOrder("size")
+	assert.NotNil(t, err)
+}
+
+func Test_GeneratorValidator(t *testing.T) {
+	const path = "<path>/example.puml"
+
+	invalidContent := []byte("@startuml\nBob -> \n@enduml")
+
+	compressed, err := gopuml.Deflate(invalidContent)
+	require.Nil(t, err)
+
+	invalidEncoded := gopuml.Encode(compressed)
+	errInvalid := errors.New("invalid")
+
+	gen := generator.New()
+	gen.SetValidator(func(_ context.Context, encoded []byte) error {
+		if bytes.Equal(encoded, invalidEncoded) {
+			return errInvalid
+		}
+
+		return nil
+	})
+
+	err = gen.PutFile(context.Background(), path, []byte(example.PUML()))
+	require.Nil(t, err)
+
+	validFile, ok := gen.GetFile(path)
+	require.True(t, ok)
+	assert.Nil(t, validFile.Err)
+	assert.Equal(t, validFile.Encoded, validFile.LastValidEncoded)
+
+	err = gen.PutFile(context.Background(), path, invalidContent)
+	require.Nil(t, err)
+
+	invalidFile, ok := gen.GetFile(path)
+	require.True(t, ok)
+	assert.Equal(t, errInvalid, invalidFile.Err)
+	assert.NotEqual(t, invalidFile.Encoded, invalidFile.LastValidEncoded)
+	assert.Equal(t, validFile.Encoded, invalidFile.LastValidEncoded)
+}
+
+func Test_GeneratorCancelled(t *testing.T) {
+	const path = "/tmp/cancelled.puml"
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	gen := generator.New()
+	gen.SetValidator(func(ctx context.Context, _ []byte) error {
+		cancel()
+		return ctx.Err()
+	})
+
+	err := gen.PutFile(ctx, path, []byte(example.PUML()))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, ok := gen.GetFile(path)
+	assert.False(t, ok)
+}
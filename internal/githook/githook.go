@@ -0,0 +1,139 @@
+// Package githook installs the gopuml git pre-commit hook.
+//
+// An existing pre-commit hook isn't overwritten, it's renamed and run by the gopuml hook
+// before gopuml, and it's restored when the gopuml hook is uninstalled.
+package githook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Name is the name of the hook.
+	Name = "pre-commit"
+	// ChainedName is the name an existing hook is renamed to.
+	ChainedName = Name + ".before-gopuml"
+
+	// marker identifies a hook installed by gopuml.
+	marker = "# installed by gopuml"
+)
+
+// Script is the hook, which runs the chained hook, if any, and then "gopuml hook run".
+const Script = `#!/bin/sh
+` + marker + `, remove it with: gopuml hook uninstall
+
+chained="$(dirname "$0")/` + ChainedName + `"
+
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+
+exec gopuml hook run
+`
+
+// ErrNotInstalled is returned when uninstalling a hook which isn't installed by gopuml.
+var ErrNotInstalled = errors.New("the gopuml " + Name + " hook isn't installed")
+
+// HooksDir returns the directory of the hooks of the git repository of dir,
+// which respects the core.hooksPath configuration.
+func HooksDir(dir string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("couldn't find the git hooks directory: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	hooksDir := strings.TrimSpace(stdout.String())
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(dir, hooksDir)
+	}
+
+	return hooksDir, nil
+}
+
+// Installed reports if the gopuml hook is installed in the hooks directory.
+func Installed(hooksDir string) (bool, error) {
+	content, err := os.ReadFile(filepath.Join(hooksDir, Name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Contains(content, []byte(marker)), nil
+}
+
+// Install installs the gopuml hook in the hooks directory, an existing hook is renamed to ChainedName.
+// It returns false if the hook is already installed.
+func Install(hooksDir string) (bool, error) {
+	installed, err := Installed(hooksDir)
+	if err != nil || installed {
+		return false, err
+	}
+
+	path := filepath.Join(hooksDir, Name)
+	chained := filepath.Join(hooksDir, ChainedName)
+
+	if _, err = os.Stat(path); err == nil {
+		if _, err = os.Stat(chained); err == nil {
+			return false, fmt.Errorf("can't chain the existing hook, the file already exists: %s", chained)
+		}
+
+		if err = os.Rename(path, chained); err != nil {
+			return false, fmt.Errorf("couldn't rename the existing hook: %w", err)
+		}
+	}
+
+	const readWriteExecuteMode = 0750
+	if err = os.MkdirAll(hooksDir, readWriteExecuteMode); err != nil {
+		return false, fmt.Errorf("couldn't create the hooks directory: %w", err)
+	}
+
+	const executableMode = 0755
+	if err = os.WriteFile(path, []byte(Script), executableMode); err != nil { // nolint: gosec
+		return false, fmt.Errorf("couldn't write the hook: %w", err)
+	}
+
+	return true, nil
+}
+
+// Uninstall removes the gopuml hook from the hooks directory and restores the chained hook, if any.
+func Uninstall(hooksDir string) error {
+	installed, err := Installed(hooksDir)
+	if err != nil {
+		return err
+	}
+
+	if !installed {
+		return ErrNotInstalled
+	}
+
+	path := filepath.Join(hooksDir, Name)
+	chained := filepath.Join(hooksDir, ChainedName)
+
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("couldn't remove the hook: %w", err)
+	}
+
+	if _, err = os.Stat(chained); err == nil {
+		if err = os.Rename(chained, path); err != nil {
+			return fmt.Errorf("couldn't restore the chained hook: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package githook_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/githook"
+)
+
+func Test_InstallAndUninstall(t *testing.T) {
+	dir := t.TempDir()
+	hook := filepath.Join(dir, githook.Name)
+	chained := filepath.Join(dir, githook.ChainedName)
+
+	installed, err := githook.Install(dir)
+	require.Nil(t, err)
+	assert.True(t, installed)
+	assert.NoFileExists(t, chained)
+
+	installed, err = githook.Install(dir)
+	require.Nil(t, err)
+	assert.False(t, installed)
+
+	require.Nil(t, githook.Uninstall(dir))
+	assert.NoFileExists(t, hook)
+	assert.ErrorIs(t, githook.Uninstall(dir), githook.ErrNotInstalled)
+
+	writeFile(t, hook, "#!/bin/sh\nexit 0\n")
+
+	installed, err = githook.Install(dir)
+	require.Nil(t, err)
+	assert.True(t, installed)
+	assert.FileExists(t, chained)
+
+	require.Nil(t, githook.Uninstall(dir))
+	assert.NoFileExists(t, chained)
+
+	content, err := os.ReadFile(hook)
+	require.Nil(t, err)
+	assert.Equal(t, "#!/bin/sh\nexit 0\n", string(content))
+
+	assert.ErrorIs(t, githook.Uninstall(dir), githook.ErrNotInstalled)
+
+	writeFile(t, chained, "#!/bin/sh\nexit 0\n")
+
+	_, err = githook.Install(dir)
+	assert.NotNil(t, err)
+}
+
+func Test_HookIsRunByGitCommit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell script")
+	}
+
+	dir := t.TempDir()
+
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig-global"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	git(t, dir, "init", "--quiet")
+	git(t, dir, "config", "user.name", "gopuml")
+	git(t, dir, "config", "user.email", "gopuml@example.com")
+
+	// A fake gopuml and the existing hook record that they were run, and fail when their file exists.
+	bin := t.TempDir()
+	writeFile(t, filepath.Join(bin, "gopuml"), "#!/bin/sh\necho \"$@\" >> ran\n[ ! -e fail-gopuml ]\n")
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	hooksDir, err := githook.HooksDir(dir)
+	require.Nil(t, err)
+
+	writeFile(t, filepath.Join(hooksDir, githook.Name), "#!/bin/sh\necho existing >> ran\n[ ! -e fail-existing ]\n")
+
+	_, err = githook.Install(hooksDir)
+	require.Nil(t, err)
+
+	commit := func() error {
+		cmd := exec.Command("git", "commit", "--quiet", "--allow-empty", "-m", "commit")
+		cmd.Dir = dir
+
+		return cmd.Run()
+	}
+
+	require.Nil(t, commit())
+	assertRan(t, dir, "existing\nhook run\n")
+
+	writeFile(t, filepath.Join(dir, "fail-gopuml"), "")
+	assert.NotNil(t, commit())
+	assertRan(t, dir, "existing\nhook run\n")
+
+	writeFile(t, filepath.Join(dir, "fail-existing"), "")
+	assert.NotNil(t, commit())
+	assertRan(t, dir, "existing\n")
+}
+
+func assertRan(t *testing.T, dir, expected string) {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, "ran"))
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(content))
+	require.Nil(t, os.Remove(filepath.Join(dir, "ran")))
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(output))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.Nil(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.Nil(t, os.WriteFile(path, []byte(content), 0700)) // nolint: gosec
+}
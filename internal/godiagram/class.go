@@ -0,0 +1,283 @@
+package godiagram
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// ClassOptions holds the options for how to generate a class diagram.
+type ClassOptions struct {
+	// Unexported includes unexported types, fields and methods.
+	Unexported bool
+}
+
+// Class generates a class diagram of the structs and interfaces in the packages.
+//
+// Embedding is drawn as inheritance, implementation of an interface as realization
+// and fields with types in the diagram as associations.
+func Class(pkgs []*types.Package, opts ClassOptions) []byte {
+	d := classDiagram{opts: opts, included: make(map[*types.TypeName]bool)}
+
+	pkgs = append([]*types.Package(nil), pkgs...)
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Path() < pkgs[j].Path() })
+
+	for _, pkg := range pkgs {
+		for _, name := range pkg.Scope().Names() {
+			obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() || !d.isVisible(obj.Exported()) {
+				continue
+			}
+
+			switch obj.Type().Underlying().(type) {
+			case *types.Struct, *types.Interface:
+				d.types = append(d.types, obj)
+				d.included[obj] = true
+			}
+		}
+	}
+
+	d.buffer.WriteString("@startuml\n")
+
+	d.writeClasses(pkgs)
+	d.writeRelations()
+
+	d.buffer.WriteString("@enduml\n")
+
+	return d.buffer.Bytes()
+}
+
+type classDiagram struct {
+	opts     ClassOptions
+	types    []*types.TypeName
+	included map[*types.TypeName]bool
+	buffer   bytes.Buffer
+}
+
+func (d *classDiagram) isVisible(exported bool) bool {
+	return exported || d.opts.Unexported
+}
+
+func (d *classDiagram) writeClasses(pkgs []*types.Package) {
+	for _, pkg := range pkgs {
+		var pkgTypes []*types.TypeName
+
+		for _, obj := range d.types {
+			if obj.Pkg() == pkg {
+				pkgTypes = append(pkgTypes, obj)
+			}
+		}
+
+		if len(pkgTypes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&d.buffer, "package %q {\n", pkg.Path())
+
+		for _, obj := range pkgTypes {
+			d.writeClass(obj)
+		}
+
+		d.buffer.WriteString("}\n")
+	}
+}
+
+func (d *classDiagram) writeClass(obj *types.TypeName) {
+	qualifier := types.RelativeTo(obj.Pkg())
+	named, _ := obj.Type().(*types.Named)
+
+	kind := "class"
+	if types.IsInterface(obj.Type()) {
+		kind = "interface"
+	}
+
+	fmt.Fprintf(&d.buffer, "  %s %q as %s {\n", kind, displayName(obj, qualifier), typeAlias(obj))
+
+	if st, ok := obj.Type().Underlying().(*types.Struct); ok {
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if field.Embedded() || !d.isVisible(field.Exported()) {
+				continue
+			}
+
+			fmt.Fprintf(&d.buffer, "    %s%s %s\n", visibility(field), field.Name(), types.TypeString(field.Type(), qualifier))
+		}
+	}
+
+	var methods []*types.Func
+
+	if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+		for i := 0; i < iface.NumExplicitMethods(); i++ {
+			methods = append(methods, iface.ExplicitMethod(i))
+		}
+	} else if named != nil {
+		for i := 0; i < named.NumMethods(); i++ {
+			methods = append(methods, named.Method(i))
+		}
+	}
+
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name() < methods[j].Name() })
+
+	for _, method := range methods {
+		if !d.isVisible(method.Exported()) {
+			continue
+		}
+
+		signature := strings.TrimPrefix(types.TypeString(method.Type(), qualifier), "func")
+		fmt.Fprintf(&d.buffer, "    %s%s%s\n", visibility(method), method.Name(), signature)
+	}
+
+	d.buffer.WriteString("  }\n")
+}
+
+func (d *classDiagram) writeRelations() {
+	for _, obj := range d.types {
+		for _, embedded := range embeddedTypes(obj.Type()) {
+			if d.included[embedded] {
+				fmt.Fprintf(&d.buffer, "%s <|-- %s\n", typeAlias(embedded), typeAlias(obj))
+			}
+		}
+	}
+
+	for _, iface := range d.types {
+		it, ok := iface.Type().Underlying().(*types.Interface)
+		if !ok || it.Empty() {
+			continue
+		}
+
+		for _, obj := range d.types {
+			if types.IsInterface(obj.Type()) || isGeneric(obj) || isGeneric(iface) {
+				continue
+			}
+
+			if types.Implements(obj.Type(), it) || types.Implements(types.NewPointer(obj.Type()), it) {
+				fmt.Fprintf(&d.buffer, "%s <|.. %s\n", typeAlias(iface), typeAlias(obj))
+			}
+		}
+	}
+
+	for _, obj := range d.types {
+		st, ok := obj.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			if field.Embedded() || !d.isVisible(field.Exported()) {
+				continue
+			}
+
+			for _, ref := range referencedTypes(field.Type(), false) {
+				if !d.included[ref.obj] {
+					continue
+				}
+
+				multiplicity := ""
+				if ref.many {
+					multiplicity = `"*" `
+				}
+
+				fmt.Fprintf(&d.buffer, "%s --> %s%s : %s\n", typeAlias(obj), multiplicity, typeAlias(ref.obj), field.Name())
+			}
+		}
+	}
+}
+
+// embeddedTypes returns the named types embedded in the struct or interface.
+func embeddedTypes(t types.Type) []*types.TypeName {
+	var result []*types.TypeName
+
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if u.Field(i).Embedded() {
+				if named := namedType(u.Field(i).Type()); named != nil {
+					result = append(result, named.Obj())
+				}
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			if named := namedType(u.EmbeddedType(i)); named != nil {
+				result = append(result, named.Obj())
+			}
+		}
+	}
+
+	return result
+}
+
+type typeRef struct {
+	obj  *types.TypeName
+	many bool
+}
+
+// referencedTypes returns the named types referenced by the type,
+// through pointers, slices, arrays, maps and channels.
+func referencedTypes(t types.Type, many bool) []typeRef {
+	switch t := t.(type) {
+	case *types.Named:
+		return []typeRef{{obj: t.Origin().Obj(), many: many}}
+	case *types.Pointer:
+		return referencedTypes(t.Elem(), many)
+	case *types.Slice:
+		return referencedTypes(t.Elem(), true)
+	case *types.Array:
+		return referencedTypes(t.Elem(), true)
+	case *types.Chan:
+		return referencedTypes(t.Elem(), true)
+	case *types.Map:
+		return append(referencedTypes(t.Key(), true), referencedTypes(t.Elem(), true)...)
+	}
+
+	return nil
+}
+
+func namedType(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	if named, ok := t.(*types.Named); ok {
+		return named.Origin()
+	}
+
+	return nil
+}
+
+func isGeneric(obj *types.TypeName) bool {
+	named, ok := obj.Type().(*types.Named)
+	return ok && named.TypeParams().Len() > 0
+}
+
+// displayName returns the name of the type, including its type parameters.
+func displayName(obj *types.TypeName, qualifier types.Qualifier) string {
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() == 0 {
+		return obj.Name()
+	}
+
+	params := make([]string, 0, named.TypeParams().Len())
+
+	for i := 0; i < named.TypeParams().Len(); i++ {
+		param := named.TypeParams().At(i)
+		params = append(params, param.Obj().Name()+" "+types.TypeString(param.Constraint(), qualifier))
+	}
+
+	return obj.Name() + "[" + strings.Join(params, ", ") + "]"
+}
+
+func typeAlias(obj *types.TypeName) string {
+	return alias(obj.Pkg().Path() + "." + obj.Name())
+}
+
+func visibility(obj types.Object) string {
+	if obj.Exported() {
+		return "+"
+	}
+
+	return "-"
+}
@@ -0,0 +1,91 @@
+package godiagram_test
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const classSource = `package shop
+
+type Named interface {
+	Name() string
+}
+
+type Item interface {
+	Named
+	Price() int
+}
+
+type Base struct {
+	ID string
+}
+
+type Product struct {
+	Base
+	Title   string
+	Tags    []Tag
+	secret  string
+}
+
+func (p Product) Name() string { return p.Title }
+func (p *Product) Price() int  { return 0 }
+func (p Product) hidden()      {}
+
+type Tag struct {
+	Label string
+}
+
+type Cart struct {
+	Items map[string]*Product
+	owner *Base
+}
+`
+
+func Test_Class(t *testing.T) {
+	pkg := typeCheck(t, "example.com/shop", classSource)
+
+	expected := `@startuml
+package "example.com/shop" {
+  class "Base" as example_com_shop_Base {
+    +ID string
+  }
+  class "Cart" as example_com_shop_Cart {
+    +Items map[string]*Product
+  }
+  interface "Item" as example_com_shop_Item {
+    +Price() int
+  }
+  interface "Named" as example_com_shop_Named {
+    +Name() string
+  }
+  class "Product" as example_com_shop_Product {
+    +Title string
+    +Tags []Tag
+    +Name() string
+    +Price() int
+  }
+  class "Tag" as example_com_shop_Tag {
+    +Label string
+  }
+}
+example_com_shop_Named <|-- example_com_shop_Item
+example_com_shop_Base <|-- example_com_shop_Product
+example_com_shop_Item <|.. example_com_shop_Product
+example_com_shop_Named <|.. example_com_shop_Product
+example_com_shop_Cart --> "*" example_com_shop_Product : Items
+example_com_shop_Product --> "*" example_com_shop_Tag : Tags
+@enduml
+`
+
+	actual := godiagram.Class([]*types.Package{pkg}, godiagram.ClassOptions{})
+	assert.Equal(t, expected, string(actual))
+
+	actual = godiagram.Class([]*types.Package{pkg}, godiagram.ClassOptions{Unexported: true})
+	assert.Contains(t, string(actual), "    -secret string\n")
+	assert.Contains(t, string(actual), "    -hidden()\n")
+	assert.Contains(t, string(actual), "example_com_shop_Cart --> example_com_shop_Base : owner\n")
+}
@@ -0,0 +1,173 @@
+package godiagram
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+// CommentDiagram is a Plant UML diagram in a comment in a Go file.
+type CommentDiagram struct {
+	// Name is the name of the diagram, it's empty if the diagram has no name.
+	Name string
+	// Filepath is the path of the Go file.
+	Filepath string
+	// Line is the line in the Go file of the first line of the diagram.
+	Line int
+	// Lines are the lines of the diagram, from @startuml to @enduml.
+	Lines []string
+}
+
+var (
+	pumlMarkerRegexp = regexp.MustCompile(`^//puml:(\S+)\s*$`)
+	startRegexp      = regexp.MustCompile(`^\s*@start\w+`)
+	endRegexp        = regexp.MustCompile(`^\s*@end\w+`)
+)
+
+// FindCommentDiagrams finds the diagrams in the comments of the Go file, which are
+// comment blocks starting with @startuml and ending with @enduml, like:
+//
+//	// @startuml
+//	// Bob -> Alice : hello
+//	// @enduml
+//
+// or comment blocks starting with a //puml:<name> marker, where the diagram is the rest of the block.
+func FindCommentDiagrams(path string, src []byte) ([]CommentDiagram, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse Go file: %w", err)
+	}
+
+	var diagrams []CommentDiagram
+
+	for _, group := range file.Comments {
+		diagrams = append(diagrams, groupDiagrams(fset, path, group)...)
+	}
+
+	return diagrams, nil
+}
+
+type commentLine struct {
+	text   string
+	line   int
+	marker string
+}
+
+func groupDiagrams(fset *token.FileSet, path string, group *ast.CommentGroup) []CommentDiagram {
+	lines := commentLines(fset, group)
+
+	var diagrams []CommentDiagram
+
+	for idx := 0; idx < len(lines); idx++ {
+		diagram := CommentDiagram{Filepath: path, Line: lines[idx].line}
+
+		switch {
+		case lines[idx].marker != "":
+			diagram.Name = lines[idx].marker
+
+			end := idx + 1
+			for end < len(lines) && lines[end].marker == "" && !endRegexp.MatchString(lines[end].text) {
+				end++
+			}
+
+			body := texts(lines[idx+1 : minInt(end+1, len(lines))])
+			if len(body) > 0 && startRegexp.MatchString(body[0]) {
+				diagram.Line = lines[idx+1].line
+				diagram.Lines = body
+			} else {
+				diagram.Lines = append([]string{"@startuml " + diagram.Name}, body...)
+				if len(body) == 0 || !endRegexp.MatchString(body[len(body)-1]) {
+					diagram.Lines = append(diagram.Lines, "@enduml")
+				}
+			}
+
+			idx = end
+		case startRegexp.MatchString(lines[idx].text):
+			end := idx + 1
+			for end < len(lines) && !endRegexp.MatchString(lines[end].text) {
+				end++
+			}
+
+			if end == len(lines) {
+				continue
+			}
+
+			diagram.Lines = texts(lines[idx : end+1])
+			diagram.Name = source.DiagramName([]byte(diagram.Lines[0]))
+
+			idx = end
+		default:
+			continue
+		}
+
+		diagrams = append(diagrams, diagram)
+	}
+
+	return diagrams
+}
+
+// commentLines splits the comments into lines without the comment markers.
+func commentLines(fset *token.FileSet, group *ast.CommentGroup) []commentLine {
+	var lines []commentLine
+
+	for _, comment := range group.List {
+		line := fset.Position(comment.Slash).Line
+
+		if strings.HasPrefix(comment.Text, "//") {
+			text := strings.TrimPrefix(strings.TrimPrefix(comment.Text, "//"), " ")
+			cl := commentLine{line: line, text: strings.TrimRight(text, " \t\r")}
+			if matches := pumlMarkerRegexp.FindStringSubmatch(comment.Text); matches != nil {
+				cl.marker = matches[1]
+			}
+
+			lines = append(lines, cl)
+
+			continue
+		}
+
+		text := strings.TrimSuffix(strings.TrimPrefix(comment.Text, "/*"), "*/")
+
+		for idx, l := range strings.Split(text, "\n") {
+			l = strings.TrimSpace(l)
+			l = strings.TrimSpace(strings.TrimPrefix(l, "*"))
+
+			lines = append(lines, commentLine{line: line + idx, text: l})
+		}
+	}
+
+	return lines
+}
+
+func texts(lines []commentLine) []string {
+	result := make([]string, 0, len(lines))
+
+	for _, l := range lines {
+		result = append(result, l.text)
+	}
+
+	return result
+}
+
+// Content returns the content of the diagram, starting with a comment which sets the origin
+// of the diagram to the Go file, relative to the directory of the diagram file.
+func (d CommentDiagram) Content(originPath string) []byte {
+	content := source.OriginComment(source.Origin{Filepath: originPath, Line: d.Line}) + "\n" +
+		strings.Join(d.Lines, "\n") + "\n"
+
+	return []byte(content)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,68 @@
+package godiagram_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const commentsSource = `package server
+
+// Handle handles a request.
+//
+// @startuml Handle Flow
+// Client -> Server : request
+//   Server -> Store : get
+// @enduml
+func Handle() {}
+
+//puml:store
+// Server -> Store : get
+// Store --> Server : value
+func get() {}
+
+/*
+@startuml
+Bob -> Alice : hello
+@enduml
+*/
+
+// @startuml
+// unterminated
+func other() {}
+`
+
+func Test_FindCommentDiagrams(t *testing.T) {
+	diagrams, err := godiagram.FindCommentDiagrams("server.go", []byte(commentsSource))
+	require.Nil(t, err)
+	require.Len(t, diagrams, 3)
+
+	assert.Equal(t, godiagram.CommentDiagram{
+		Name:     "Handle Flow",
+		Filepath: "server.go",
+		Line:     5,
+		Lines:    []string{"@startuml Handle Flow", "Client -> Server : request", "  Server -> Store : get", "@enduml"},
+	}, diagrams[0])
+
+	assert.Equal(t, godiagram.CommentDiagram{
+		Name:     "store",
+		Filepath: "server.go",
+		Line:     11,
+		Lines:    []string{"@startuml store", "Server -> Store : get", "Store --> Server : value", "@enduml"},
+	}, diagrams[1])
+
+	assert.Equal(t, godiagram.CommentDiagram{
+		Filepath: "server.go",
+		Line:     17,
+		Lines:    []string{"@startuml", "Bob -> Alice : hello", "@enduml"},
+	}, diagrams[2])
+
+	expected := "' gopuml:origin=../server.go:11\n@startuml store\nServer -> Store : get\nStore --> Server : value\n@enduml\n"
+	assert.Equal(t, expected, string(diagrams[1].Content("../server.go")))
+
+	_, err = godiagram.FindCommentDiagrams("broken.go", []byte("package"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,300 @@
+package godiagram
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// PackageKind is the kind of a package in an import graph.
+type PackageKind string
+
+const (
+	// KindModule is a package in the main module.
+	KindModule PackageKind = "module"
+	// KindStdlib is a package in the standard library.
+	KindStdlib PackageKind = "stdlib"
+	// KindThirdParty is a package in a dependency of the main module.
+	KindThirdParty PackageKind = "third-party"
+)
+
+var kindColors = map[PackageKind]string{
+	KindModule:     "#LightBlue",
+	KindStdlib:     "#LightGray",
+	KindThirdParty: "#Wheat",
+}
+
+const cycleColor = "#Red"
+
+// ImportGraph is the graph of the imports of packages.
+type ImportGraph struct {
+	// Roots are the paths of the packages the graph was loaded from.
+	Roots []string
+	// Packages are the packages in the graph by their path.
+	Packages map[string]Package
+}
+
+// Package is a package in an import graph.
+type Package struct {
+	Path    string
+	Kind    PackageKind
+	Imports []string
+}
+
+// NewImportGraph creates the import graph of the loaded packages.
+func NewImportGraph(pkgs []*packages.Package) ImportGraph {
+	graph := ImportGraph{Packages: make(map[string]Package)}
+
+	for _, pkg := range pkgs {
+		graph.Roots = append(graph.Roots, pkg.PkgPath)
+	}
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		p := Package{Path: pkg.PkgPath, Kind: packageKind(pkg)}
+
+		for path := range pkg.Imports {
+			p.Imports = append(p.Imports, path)
+		}
+
+		sort.Strings(p.Imports)
+
+		graph.Packages[p.Path] = p
+	})
+
+	return graph
+}
+
+func packageKind(pkg *packages.Package) PackageKind {
+	switch {
+	case pkg.Module != nil && pkg.Module.Main:
+		return KindModule
+	case pkg.Module != nil:
+		return KindThirdParty
+	case !strings.Contains(strings.Split(pkg.PkgPath, "/")[0], "."):
+		return KindStdlib
+	default:
+		return KindThirdParty
+	}
+}
+
+// DepsOptions holds the options for how to generate an import graph diagram.
+type DepsOptions struct {
+	// Internal only includes packages in the main module.
+	Internal bool
+	// All follows the imports of packages outside the main module, which are otherwise
+	// drawn without their imports.
+	All bool
+	// Depth limits the number of imports followed from the roots, 0 means no limit.
+	Depth int
+	// Collapse collapses the packages with any of the path prefixes into one package per prefix.
+	Collapse []string
+	// Exclude excludes the packages with a path matching any of the expressions.
+	Exclude []*regexp.Regexp
+}
+
+// Deps generates a component diagram of the import graph.
+// Packages are colored by their kind, and imports which are part of a cycle are highlighted.
+func Deps(graph ImportGraph, opts DepsOptions) []byte {
+	nodes := make(map[string]PackageKind)
+	edges := make(map[string]map[string]bool)
+
+	reachable := reachablePackages(graph, opts)
+
+	for path := range reachable {
+		node := collapsedPath(path, opts.Collapse)
+		if _, ok := nodes[node]; !ok || graph.Packages[path].Kind == KindModule {
+			nodes[node] = graph.Packages[path].Kind
+		}
+	}
+
+	for path := range reachable {
+		from := collapsedPath(path, opts.Collapse)
+
+		for _, imported := range graph.Packages[path].Imports {
+			to := collapsedPath(imported, opts.Collapse)
+			if !reachable[imported] || to == from {
+				continue
+			}
+
+			if edges[from] == nil {
+				edges[from] = make(map[string]bool)
+			}
+
+			edges[from][to] = true
+		}
+	}
+
+	component := stronglyConnectedComponents(nodes, edges)
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("@startuml\n")
+
+	for _, node := range sortedKeys(nodes) {
+		fmt.Fprintf(&buffer, "component %q as %s %s\n", node, alias(node), kindColors[nodes[node]])
+	}
+
+	for _, from := range sortedKeys(nodes) {
+		for _, to := range sortedKeys(edges[from]) {
+			arrow := "-->"
+			if component[from] == component[to] {
+				arrow = "-[" + cycleColor + "]->"
+			}
+
+			fmt.Fprintf(&buffer, "%s %s %s\n", alias(from), arrow, alias(to))
+		}
+	}
+
+	buffer.WriteString("legend right\n")
+
+	for _, kind := range []PackageKind{KindModule, KindThirdParty, KindStdlib} {
+		fmt.Fprintf(&buffer, "  <back:%s>   </back> %s\n", kindColors[kind], kind)
+	}
+
+	fmt.Fprintf(&buffer, "  <color:%s>--></color> import cycle\n", cycleColor)
+	buffer.WriteString("endlegend\n")
+	buffer.WriteString("@enduml\n")
+
+	return buffer.Bytes()
+}
+
+// reachablePackages returns the included packages reachable from the roots within the depth.
+// The imports of packages outside the main module are only followed from the roots or with the All option.
+func reachablePackages(graph ImportGraph, opts DepsOptions) map[string]bool {
+	reachable := make(map[string]bool)
+
+	var queue []string
+
+	for _, root := range graph.Roots {
+		if isIncluded(graph, root, opts) && !reachable[root] {
+			reachable[root] = true
+			queue = append(queue, root)
+		}
+	}
+
+	for depth := 1; len(queue) > 0 && (opts.Depth <= 0 || depth <= opts.Depth); depth++ {
+		var next []string
+
+		for _, path := range queue {
+			if !opts.All && depth > 1 && graph.Packages[path].Kind != KindModule {
+				continue
+			}
+
+			for _, imported := range graph.Packages[path].Imports {
+				if isIncluded(graph, imported, opts) && !reachable[imported] {
+					reachable[imported] = true
+					next = append(next, imported)
+				}
+			}
+		}
+
+		queue = next
+	}
+
+	return reachable
+}
+
+func isIncluded(graph ImportGraph, path string, opts DepsOptions) bool {
+	pkg, ok := graph.Packages[path]
+	if !ok || opts.Internal && pkg.Kind != KindModule {
+		return false
+	}
+
+	for _, exclude := range opts.Exclude {
+		if exclude.MatchString(path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collapsedPath returns the longest of the prefixes the path is collapsed into,
+// or the path itself.
+func collapsedPath(path string, prefixes []string) string {
+	result := path
+	longest := -1
+
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && len(prefix) > longest {
+			result, longest = prefix, len(prefix)
+		}
+	}
+
+	return result
+}
+
+// stronglyConnectedComponents returns the index of the strongly connected component of every node,
+// using Tarjan's algorithm. Nodes which aren't part of a cycle get a component of their own.
+func stronglyConnectedComponents(nodes map[string]PackageKind, edges map[string]map[string]bool) map[string]int {
+	var (
+		index     = make(map[string]int)
+		lowlink   = make(map[string]int)
+		onStack   = make(map[string]bool)
+		component = make(map[string]int)
+		stack     []string
+		counter   int
+		connect   func(node string)
+	)
+
+	connect = func(node string) {
+		index[node], lowlink[node] = counter, counter
+		counter++
+
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, next := range sortedKeys(edges[node]) {
+			if _, visited := index[next]; !visited {
+				connect(next)
+
+				if lowlink[next] < lowlink[node] {
+					lowlink[node] = lowlink[next]
+				}
+			} else if onStack[next] && index[next] < lowlink[node] {
+				lowlink[node] = index[next]
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component[last] = index[node]
+
+			if last == node {
+				return
+			}
+		}
+	}
+
+	for _, node := range sortedKeys(nodes) {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+
+	return component
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
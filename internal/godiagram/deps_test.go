@@ -0,0 +1,70 @@
+package godiagram_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+func Test_Deps(t *testing.T) {
+	graph := godiagram.ImportGraph{
+		Roots: []string{"example.com/app/cmd"},
+		Packages: map[string]godiagram.Package{
+			"example.com/app/cmd":     {Path: "example.com/app/cmd", Kind: godiagram.KindModule, Imports: []string{"example.com/app/core/a", "fmt"}},
+			"example.com/app/core/a":  {Path: "example.com/app/core/a", Kind: godiagram.KindModule, Imports: []string{"example.com/app/core/b"}},
+			"example.com/app/core/b":  {Path: "example.com/app/core/b", Kind: godiagram.KindModule, Imports: []string{"example.com/app/core/a", "github.com/x/y"}},
+			"fmt":                     {Path: "fmt", Kind: godiagram.KindStdlib},
+			"github.com/x/y":          {Path: "github.com/x/y", Kind: godiagram.KindThirdParty, Imports: []string{"github.com/x/y/internal"}},
+			"github.com/x/y/internal": {Path: "github.com/x/y/internal", Kind: godiagram.KindThirdParty},
+		},
+	}
+
+	const legend = "legend right\n" +
+		"  <back:#LightBlue>   </back> module\n" +
+		"  <back:#Wheat>   </back> third-party\n" +
+		"  <back:#LightGray>   </back> stdlib\n" +
+		"  <color:#Red>--></color> import cycle\n" +
+		"endlegend\n"
+
+	expected := "@startuml\n" +
+		`component "example.com/app/cmd" as example_com_app_cmd #LightBlue` + "\n" +
+		`component "example.com/app/core/a" as example_com_app_core_a #LightBlue` + "\n" +
+		`component "example.com/app/core/b" as example_com_app_core_b #LightBlue` + "\n" +
+		"example_com_app_cmd --> example_com_app_core_a\n" +
+		"example_com_app_core_a -[#Red]-> example_com_app_core_b\n" +
+		"example_com_app_core_b -[#Red]-> example_com_app_core_a\n" +
+		legend +
+		"@enduml\n"
+
+	actual := godiagram.Deps(graph, godiagram.DepsOptions{Internal: true})
+	assert.Equal(t, expected, string(actual))
+
+	expected = "@startuml\n" +
+		`component "example.com/app/cmd" as example_com_app_cmd #LightBlue` + "\n" +
+		`component "example.com/app/core" as example_com_app_core #LightBlue` + "\n" +
+		`component "github.com/x/y" as github_com_x_y #Wheat` + "\n" +
+		"example_com_app_cmd --> example_com_app_core\n" +
+		"example_com_app_core --> github_com_x_y\n" +
+		legend +
+		"@enduml\n"
+
+	actual = godiagram.Deps(graph, godiagram.DepsOptions{
+		Collapse: []string{"example.com/app/core", "github.com/x/y"},
+		Exclude:  []*regexp.Regexp{regexp.MustCompile(`^fmt$`)},
+	})
+	assert.Equal(t, expected, string(actual))
+
+	actual = godiagram.Deps(graph, godiagram.DepsOptions{})
+	assert.Contains(t, string(actual), `component "github.com/x/y" as github_com_x_y #Wheat`)
+	assert.NotContains(t, string(actual), "github.com/x/y/internal")
+
+	actual = godiagram.Deps(graph, godiagram.DepsOptions{All: true})
+	assert.Contains(t, string(actual), "github_com_x_y --> github_com_x_y_internal\n")
+
+	actual = godiagram.Deps(graph, godiagram.DepsOptions{Depth: 1})
+	assert.Contains(t, string(actual), `component "fmt" as fmt #LightGray`)
+	assert.NotContains(t, string(actual), "core/b")
+}
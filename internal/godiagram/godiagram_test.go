@@ -0,0 +1,29 @@
+package godiagram_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// typeCheck parses and type checks the source as a package with the given path.
+func typeCheck(t *testing.T, path, src string) *types.Package {
+	t.Helper()
+
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path+".go", src, parser.ParseComments)
+	require.Nil(t, err)
+
+	conf := types.Config{Importer: importer.Default()}
+
+	pkg, err := conf.Check(path, fset, []*ast.File{file}, nil)
+	require.Nil(t, err)
+
+	return pkg
+}
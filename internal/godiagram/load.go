@@ -0,0 +1,79 @@
+// Package godiagram generates Plant UML diagrams from Go source code.
+package godiagram
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	filesLoadMode   = packages.NeedName | packages.NeedFiles
+	importsLoadMode = packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedModule
+	typesLoadMode   = importsLoadMode | packages.NeedFiles | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo
+)
+
+// LoadPackages loads and type checks the packages matching the patterns, like "./...",
+// relative to the directory. The current directory is used if the directory is empty.
+func LoadPackages(dir string, patterns []string) ([]*packages.Package, error) {
+	return load(dir, patterns, typesLoadMode)
+}
+
+// LoadImports loads the packages matching the patterns and their imports, without type checking them.
+func LoadImports(dir string, patterns []string) ([]*packages.Package, error) {
+	return load(dir, patterns, importsLoadMode)
+}
+
+// LoadFiles returns the Go files of the packages matching the patterns.
+func LoadFiles(dir string, patterns []string) ([]string, error) {
+	pkgs, err := load(dir, patterns, filesLoadMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+
+	for _, pkg := range pkgs {
+		files = append(files, pkg.GoFiles...)
+	}
+
+	return files, nil
+}
+
+func load(dir string, patterns []string, mode packages.LoadMode) ([]*packages.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: mode, Dir: dir, Tests: false}, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load packages: %w", err)
+	}
+
+	var pkgErrs []string
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			pkgErrs = append(pkgErrs, err.Error())
+		}
+	})
+
+	if len(pkgErrs) > 0 {
+		return nil, errors.New("couldn't load packages:\n  " + strings.Join(pkgErrs, "\n  "))
+	}
+
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages found for: %s", strings.Join(patterns, " "))
+	}
+
+	return pkgs, nil
+}
+
+// alias converts the name into a Plant UML alias, which only contains letters, digits and underscores.
+func alias(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+
+		return '_'
+	}, name)
+}
@@ -0,0 +1,231 @@
+package godiagram
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ModuleGraph is the graph of the requirements of modules,
+// where modules are identified by their path and version, like "golang.org/x/mod@v0.21.0",
+// and the main module by its path.
+type ModuleGraph struct {
+	// Main is the path of the main module.
+	Main string
+	// Requirements are the requirements of the modules.
+	Requirements []ModuleRequirement
+	// Indirect are the paths of the modules which are indirect requirements of the main module.
+	Indirect map[string]bool
+	// Replaced are the replacements of the modules, by module path or module path and version.
+	Replaced map[string]string
+}
+
+// ModuleRequirement is a requirement of one module on another.
+type ModuleRequirement struct {
+	From string
+	To   string
+}
+
+// ParseGoMod parses a go.mod file into the graph of the requirements of the main module.
+func ParseGoMod(content []byte) (ModuleGraph, error) {
+	file, err := modfile.Parse("go.mod", content, nil)
+	if err != nil {
+		return ModuleGraph{}, fmt.Errorf("couldn't parse go.mod: %w", err)
+	}
+
+	if file.Module == nil {
+		return ModuleGraph{}, fmt.Errorf("couldn't parse go.mod: missing module directive")
+	}
+
+	graph := newModuleGraph(file.Module.Mod.Path)
+
+	for _, require := range file.Require {
+		graph.Requirements = append(graph.Requirements, ModuleRequirement{
+			From: graph.Main,
+			To:   require.Mod.Path + "@" + require.Mod.Version,
+		})
+
+		if require.Indirect {
+			graph.Indirect[require.Mod.Path] = true
+		}
+	}
+
+	graph.addReplacements(file)
+
+	return graph, nil
+}
+
+// ParseModGraph parses the output of "go mod graph" into a graph of requirements.
+func ParseModGraph(content []byte) (ModuleGraph, error) {
+	graph := newModuleGraph("")
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 2 { // nolint: gomnd
+			return ModuleGraph{}, fmt.Errorf("couldn't parse module graph: line %d: expected two modules", lineNumber)
+		}
+
+		if graph.Main == "" && !strings.Contains(fields[0], "@") {
+			graph.Main = fields[0]
+		}
+
+		// The go and toolchain versions are listed as requirements since Go 1.21.
+		if isToolchainRequirement(fields[1]) {
+			continue
+		}
+
+		graph.Requirements = append(graph.Requirements, ModuleRequirement{From: fields[0], To: fields[1]})
+	}
+
+	return graph, scanner.Err()
+}
+
+// Merge adds the indirect requirements and the replacements of the main module in the go.mod graph to the graph.
+func (graph *ModuleGraph) Merge(goMod ModuleGraph) {
+	if graph.Main == "" {
+		graph.Main = goMod.Main
+	}
+
+	for path := range goMod.Indirect {
+		graph.Indirect[path] = true
+	}
+
+	for mod, replacement := range goMod.Replaced {
+		graph.Replaced[mod] = replacement
+	}
+}
+
+func newModuleGraph(main string) ModuleGraph {
+	return ModuleGraph{Main: main, Indirect: make(map[string]bool), Replaced: make(map[string]string)}
+}
+
+func (graph *ModuleGraph) addReplacements(file *modfile.File) {
+	for _, replace := range file.Replace {
+		mod := replace.Old.Path
+		if replace.Old.Version != "" {
+			mod += "@" + replace.Old.Version
+		}
+
+		replacement := replace.New.Path
+		if replace.New.Version != "" {
+			replacement += "@" + replace.New.Version
+		}
+
+		graph.Replaced[mod] = replacement
+	}
+}
+
+func isToolchainRequirement(mod string) bool {
+	path, _, _ := strings.Cut(mod, "@")
+	return path == "go" || path == "toolchain"
+}
+
+var moduleColors = map[string]string{
+	"main":     "#LightBlue",
+	"replaced": "#Wheat",
+	"multiple": "#Pink",
+	"indirect": "#LightGray",
+}
+
+// ModGraph generates a component diagram of the module graph.
+// Replaced modules, indirect requirements and modules required in multiple versions are highlighted.
+func ModGraph(graph ModuleGraph) []byte {
+	nodes := make(map[string]bool)
+	versions := make(map[string]map[string]bool)
+
+	for _, req := range graph.Requirements {
+		for _, mod := range []string{req.From, req.To} {
+			nodes[mod] = true
+
+			path, version, _ := strings.Cut(mod, "@")
+			if versions[path] == nil {
+				versions[path] = make(map[string]bool)
+			}
+
+			versions[path][version] = true
+		}
+	}
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("@startuml\n")
+
+	for _, mod := range sortedKeys(nodes) {
+		path, version, _ := strings.Cut(mod, "@")
+
+		name := path
+		if version != "" {
+			name += `\n` + version
+		}
+
+		color := ""
+
+		switch {
+		case mod == graph.Main:
+			color = " " + moduleColors["main"]
+		case graph.replacement(mod) != "":
+			color = " " + moduleColors["replaced"]
+		case len(versions[path]) > 1:
+			color = " " + moduleColors["multiple"]
+		case graph.Indirect[path]:
+			color = " " + moduleColors["indirect"]
+		}
+
+		fmt.Fprintf(&buffer, "component \"%s\" as %s%s\n", name, alias(mod), color)
+
+		if replacement := graph.replacement(mod); replacement != "" {
+			fmt.Fprintf(&buffer, "note bottom of %s : replaced by %s\n", alias(mod), replacement)
+		}
+	}
+
+	requirements := append([]ModuleRequirement(nil), graph.Requirements...)
+	sort.SliceStable(requirements, func(i, j int) bool {
+		if requirements[i].From != requirements[j].From {
+			return requirements[i].From < requirements[j].From
+		}
+
+		return requirements[i].To < requirements[j].To
+	})
+
+	for _, req := range requirements {
+		path, _, _ := strings.Cut(req.To, "@")
+
+		arrow := "-->"
+		if req.From == graph.Main && graph.Indirect[path] {
+			arrow = "..>"
+		}
+
+		fmt.Fprintf(&buffer, "%s %s %s\n", alias(req.From), arrow, alias(req.To))
+	}
+
+	buffer.WriteString("legend right\n")
+	fmt.Fprintf(&buffer, "  <back:%s>   </back> main module\n", moduleColors["main"])
+	fmt.Fprintf(&buffer, "  <back:%s>   </back> replaced\n", moduleColors["replaced"])
+	fmt.Fprintf(&buffer, "  <back:%s>   </back> multiple versions\n", moduleColors["multiple"])
+	fmt.Fprintf(&buffer, "  <back:%s>   </back> indirect\n", moduleColors["indirect"])
+	buffer.WriteString("endlegend\n")
+	buffer.WriteString("@enduml\n")
+
+	return buffer.Bytes()
+}
+
+// replacement returns the replacement of the module, by its path and version or only by its path.
+func (graph ModuleGraph) replacement(mod string) string {
+	if replacement, ok := graph.Replaced[mod]; ok {
+		return replacement
+	}
+
+	path, _, _ := strings.Cut(mod, "@")
+
+	return graph.Replaced[path]
+}
@@ -0,0 +1,82 @@
+package godiagram_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+const goMod = `module example.com/app
+
+go 1.22
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.2.0 // indirect
+)
+
+replace example.com/a => ../a
+`
+
+const modGraph = `example.com/app example.com/a@v1.0.0
+example.com/app example.com/b@v1.2.0
+example.com/app go@1.22
+example.com/a@v1.0.0 example.com/b@v1.1.0
+`
+
+const modGraphLegend = "legend right\n" +
+	"  <back:#LightBlue>   </back> main module\n" +
+	"  <back:#Wheat>   </back> replaced\n" +
+	"  <back:#Pink>   </back> multiple versions\n" +
+	"  <back:#LightGray>   </back> indirect\n" +
+	"endlegend\n"
+
+func Test_ModGraphFromGoMod(t *testing.T) {
+	graph, err := godiagram.ParseGoMod([]byte(goMod))
+	require.Nil(t, err)
+
+	expected := "@startuml\n" +
+		`component "example.com/a\nv1.0.0" as example_com_a_v1_0_0 #Wheat` + "\n" +
+		"note bottom of example_com_a_v1_0_0 : replaced by ../a\n" +
+		`component "example.com/app" as example_com_app #LightBlue` + "\n" +
+		`component "example.com/b\nv1.2.0" as example_com_b_v1_2_0 #LightGray` + "\n" +
+		"example_com_app --> example_com_a_v1_0_0\n" +
+		"example_com_app ..> example_com_b_v1_2_0\n" +
+		modGraphLegend +
+		"@enduml\n"
+
+	assert.Equal(t, expected, string(godiagram.ModGraph(graph)))
+
+	_, err = godiagram.ParseGoMod([]byte("go 1.22\n"))
+	assert.NotNil(t, err)
+}
+
+func Test_ModGraphFromModGraph(t *testing.T) {
+	graph, err := godiagram.ParseModGraph([]byte(modGraph))
+	require.Nil(t, err)
+
+	goModGraph, err := godiagram.ParseGoMod([]byte(goMod))
+	require.Nil(t, err)
+
+	graph.Merge(goModGraph)
+
+	expected := "@startuml\n" +
+		`component "example.com/a\nv1.0.0" as example_com_a_v1_0_0 #Wheat` + "\n" +
+		"note bottom of example_com_a_v1_0_0 : replaced by ../a\n" +
+		`component "example.com/app" as example_com_app #LightBlue` + "\n" +
+		`component "example.com/b\nv1.1.0" as example_com_b_v1_1_0 #Pink` + "\n" +
+		`component "example.com/b\nv1.2.0" as example_com_b_v1_2_0 #Pink` + "\n" +
+		"example_com_a_v1_0_0 --> example_com_b_v1_1_0\n" +
+		"example_com_app --> example_com_a_v1_0_0\n" +
+		"example_com_app ..> example_com_b_v1_2_0\n" +
+		modGraphLegend +
+		"@enduml\n"
+
+	assert.Equal(t, expected, string(godiagram.ModGraph(graph)))
+
+	_, err = godiagram.ParseModGraph([]byte("example.com/app\n"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,399 @@
+package godiagram
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// SequenceOptions holds the options for how to generate a sequence diagram.
+type SequenceOptions struct {
+	// Entry is the function to start from, like "example.com/pkg.Func" or "example.com/pkg.Type.Method".
+	Entry string
+	// Depth limits the number of nested calls followed from the entry, 0 means no limit.
+	Depth int
+	// Include only draws calls to packages with a path matching any of the expressions,
+	// calls to packages in the main module are drawn if it's empty.
+	Include []*regexp.Regexp
+	// Exclude doesn't draw calls to packages with a path matching any of the expressions.
+	Exclude []*regexp.Regexp
+	// ByType uses the receiver types of methods as participants instead of their packages.
+	ByType bool
+}
+
+// Sequence generates a sequence diagram of the static call graph from the entry function.
+// The packages must have been loaded by LoadPackages, only calls in their functions are followed.
+//
+// Packages, or receiver types, are participants and calls are messages.
+// A call through an interface is drawn as a message to the interface,
+// with a note listing the implementations in the packages.
+// Every call, except goroutines, activates the called participant until it returns to the caller.
+// The calls of a function are drawn once, later calls to it refer to them.
+func Sequence(pkgs []*packages.Package, opts SequenceOptions) ([]byte, error) {
+	prog, ssaPkgs := ssautil.Packages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+
+	entry, err := findFunction(prog, opts.Entry)
+	if err != nil {
+		return nil, err
+	}
+
+	s := sequenceDiagram{
+		opts:         opts,
+		mainPackages: mainModulePackages(pkgs),
+		onStack:      make(map[*ssa.Function]bool),
+		expanded:     make(map[*ssa.Function]int),
+		hasCalls:     make(map[*ssa.Function]bool),
+		seen:         make(map[string]bool),
+	}
+
+	for _, pkg := range ssaPkgs {
+		if pkg != nil {
+			s.namedTypes = append(s.namedTypes, packageTypes(pkg.Pkg)...)
+		}
+	}
+
+	entryParticipant := s.participant(entry)
+
+	fmt.Fprintf(&s.body, "activate %s\n", entryParticipant)
+	s.walk(entry, 1)
+	fmt.Fprintf(&s.body, "deactivate %s\n", entryParticipant)
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("@startuml\n")
+
+	for _, p := range s.participants {
+		fmt.Fprintf(&buffer, "participant %q as %s\n", p.name, p.alias)
+	}
+
+	buffer.Write(s.body.Bytes())
+	buffer.WriteString("@enduml\n")
+
+	return buffer.Bytes(), nil
+}
+
+type sequenceParticipant struct {
+	name  string
+	alias string
+}
+
+type sequenceDiagram struct {
+	opts         SequenceOptions
+	mainPackages map[string]bool
+	namedTypes   []*types.TypeName
+
+	participants []sequenceParticipant
+	seen         map[string]bool
+	onStack      map[*ssa.Function]bool
+	// expanded holds the depth every walked function was walked from, a function isn't walked
+	// again from the same or a larger depth. hasCalls holds the walked functions which drew calls.
+	expanded map[*ssa.Function]int
+	hasCalls map[*ssa.Function]bool
+	body     bytes.Buffer
+}
+
+const goPrefix = "go "
+
+// walk writes the calls in the function, in the order of its blocks, and follows them.
+func (s *sequenceDiagram) walk(fn *ssa.Function, depth int) {
+	if s.onStack[fn] {
+		return
+	}
+
+	s.onStack[fn] = true
+	defer delete(s.onStack, fn)
+
+	s.expanded[fn] = depth
+
+	from := s.participant(fn)
+
+	for _, block := range fn.Blocks {
+		for _, instr := range block.Instrs {
+			call, ok := instr.(ssa.CallInstruction)
+			if !ok {
+				continue
+			}
+
+			prefix := ""
+
+			switch instr.(type) {
+			case *ssa.Go:
+				prefix = goPrefix
+			case *ssa.Defer:
+				prefix = "defer "
+			}
+
+			s.writeCall(from, prefix, call.Common(), depth)
+		}
+	}
+}
+
+func (s *sequenceDiagram) writeCall(from, prefix string, common *ssa.CallCommon, depth int) {
+	if common.IsInvoke() {
+		iface := namedType(common.Value.Type())
+		if iface == nil || !s.isIncluded(iface.Obj().Pkg()) {
+			return
+		}
+
+		to := s.typeParticipant(iface.Obj())
+		fmt.Fprintf(&s.body, "%s -> %s : %s%s()\n", from, to, prefix, common.Method.Name())
+		s.writeImplementations(to, iface.Obj())
+
+		if prefix != goPrefix {
+			s.writeActivation(from, to, func() {})
+		}
+
+		return
+	}
+
+	callee := common.StaticCallee()
+	if callee == nil {
+		return
+	}
+
+	// Anonymous functions are part of the function they are declared in.
+	if callee.Parent() != nil {
+		if prefix == "" {
+			s.walk(callee, depth)
+		}
+
+		return
+	}
+
+	if callee.Pkg == nil && callee.Origin() != nil {
+		callee = callee.Origin()
+	}
+
+	if !s.isIncluded(functionPackage(callee)) {
+		return
+	}
+
+	to := s.participant(callee)
+	fmt.Fprintf(&s.body, "%s -> %s : %s%s()\n", from, to, prefix, callee.Name())
+
+	follow := func() {
+		if s.opts.Depth > 0 && depth >= s.opts.Depth {
+			return
+		}
+
+		// The calls of a function are drawn once, later calls refer to them.
+		if expandedDepth, ok := s.expanded[callee]; ok && expandedDepth <= depth+1 {
+			if s.hasCalls[callee] {
+				fmt.Fprintf(&s.body, "ref over %s : %s(), drawn above\n", to, callee.Name())
+			}
+
+			return
+		}
+
+		start := s.body.Len()
+		s.walk(callee, depth+1)
+		s.hasCalls[callee] = s.body.Len() > start
+	}
+
+	// A goroutine doesn't return to the caller, so it's drawn without an activation.
+	if prefix == goPrefix {
+		follow()
+		return
+	}
+
+	s.writeActivation(from, to, follow)
+}
+
+// writeActivation writes an activation of the participant called from the caller around the nested calls,
+// which ends with a return to the caller.
+func (s *sequenceDiagram) writeActivation(caller, participant string, nested func()) {
+	fmt.Fprintf(&s.body, "activate %s\n", participant)
+	nested()
+	fmt.Fprintf(&s.body, "%s --> %s\ndeactivate %s\n", participant, caller, participant)
+}
+
+func (s *sequenceDiagram) writeImplementations(participant string, iface *types.TypeName) {
+	it, ok := iface.Type().Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+
+	var implementations []string
+
+	for _, obj := range s.namedTypes {
+		if types.IsInterface(obj.Type()) || isGeneric(obj) {
+			continue
+		}
+
+		if types.Implements(obj.Type(), it) || types.Implements(types.NewPointer(obj.Type()), it) {
+			implementations = append(implementations, obj.Pkg().Name()+"."+obj.Name())
+		}
+	}
+
+	if len(implementations) == 0 {
+		return
+	}
+
+	sort.Strings(implementations)
+
+	fmt.Fprintf(&s.body, "note right of %s\n  implemented by:\n", participant)
+
+	for _, implementation := range implementations {
+		fmt.Fprintf(&s.body, "  * %s\n", implementation)
+	}
+
+	s.body.WriteString("end note\n")
+}
+
+func (s *sequenceDiagram) isIncluded(pkg *types.Package) bool {
+	if pkg == nil {
+		return false
+	}
+
+	for _, exclude := range s.opts.Exclude {
+		if exclude.MatchString(pkg.Path()) {
+			return false
+		}
+	}
+
+	if len(s.opts.Include) == 0 {
+		return s.mainPackages[pkg.Path()]
+	}
+
+	for _, include := range s.opts.Include {
+		if include.MatchString(pkg.Path()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// participant returns the alias of the participant of the function, which is
+// its package or, for methods, its receiver type if participants are types.
+func (s *sequenceDiagram) participant(fn *ssa.Function) string {
+	if recv := fn.Signature.Recv(); recv != nil && s.opts.ByType {
+		if named := namedType(recv.Type()); named != nil {
+			return s.typeParticipant(named.Obj())
+		}
+	}
+
+	pkg := functionPackage(fn)
+	if pkg == nil {
+		return s.addParticipant(fn.String(), fn.String())
+	}
+
+	return s.addParticipant(pkg.Path(), pkg.Path())
+}
+
+func (s *sequenceDiagram) typeParticipant(obj *types.TypeName) string {
+	if !s.opts.ByType {
+		return s.addParticipant(obj.Pkg().Path(), obj.Pkg().Path())
+	}
+
+	return s.addParticipant(obj.Pkg().Name()+"."+obj.Name(), obj.Pkg().Path()+"."+obj.Name())
+}
+
+func (s *sequenceDiagram) addParticipant(name, id string) string {
+	a := alias(id)
+
+	if !s.seen[a] {
+		s.seen[a] = true
+		s.participants = append(s.participants, sequenceParticipant{name: name, alias: a})
+	}
+
+	return a
+}
+
+func functionPackage(fn *ssa.Function) *types.Package {
+	if fn.Pkg != nil {
+		return fn.Pkg.Pkg
+	}
+
+	if obj := fn.Object(); obj != nil {
+		return obj.Pkg()
+	}
+
+	return nil
+}
+
+// findFunction finds the function with the name, like "example.com/pkg.Func" or "example.com/pkg.Type.Method".
+// The package is the loaded package with the longest path which prefixes the name,
+// since the last element of a package path can contain dots, like "gopkg.in/yaml.v3".
+func findFunction(prog *ssa.Program, name string) (*ssa.Function, error) {
+	invalidErr := fmt.Errorf("invalid entry: [%s], expected <package path>.<function> or <package path>.<type>.<method>", name)
+
+	if !strings.Contains(name[strings.LastIndex(name, "/")+1:], ".") {
+		return nil, invalidErr
+	}
+
+	var pkg *ssa.Package
+
+	for _, candidate := range prog.AllPackages() {
+		pkgPath := candidate.Pkg.Path()
+
+		if strings.HasPrefix(name, pkgPath+".") && (pkg == nil || len(pkgPath) > len(pkg.Pkg.Path())) {
+			pkg = candidate
+		}
+	}
+
+	if pkg == nil {
+		return nil, fmt.Errorf("package not loaded: [%s]", name)
+	}
+
+	pkgPath := pkg.Pkg.Path()
+
+	parts := strings.Split(strings.TrimPrefix(name, pkgPath+"."), ".")
+	if len(parts) > 2 { // nolint: gomnd
+		return nil, invalidErr
+	}
+
+	if len(parts) == 1 {
+		if fn := pkg.Func(parts[0]); fn != nil {
+			return fn, nil
+		}
+
+		return nil, fmt.Errorf("function not found: [%s]", name)
+	}
+
+	typ := pkg.Type(parts[0])
+	if typ == nil {
+		return nil, fmt.Errorf("type not found: [%s.%s]", pkgPath, parts[0])
+	}
+
+	selection := prog.MethodSets.MethodSet(types.NewPointer(typ.Type())).Lookup(pkg.Pkg, parts[1])
+	if selection == nil {
+		return nil, fmt.Errorf("method not found: [%s]", name)
+	}
+
+	return prog.MethodValue(selection), nil
+}
+
+// mainModulePackages returns the paths of the loaded packages in the main module.
+func mainModulePackages(pkgs []*packages.Package) map[string]bool {
+	result := make(map[string]bool)
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if packageKind(pkg) == KindModule {
+			result[pkg.PkgPath] = true
+		}
+	})
+
+	return result
+}
+
+// packageTypes returns the named types declared in the package.
+func packageTypes(pkg *types.Package) []*types.TypeName {
+	var result []*types.TypeName
+
+	for _, name := range pkg.Scope().Names() {
+		if obj, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok && !obj.IsAlias() {
+			result = append(result, obj)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,197 @@
+package godiagram_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/godiagram"
+)
+
+var sequenceModule = map[string]string{
+	"go.mod": "module example.com/app\n\ngo 1.20\n",
+	"server/server.go": `package server
+
+import (
+	"strings"
+
+	"example.com/app/store"
+)
+
+type Server struct {
+	Store store.Store
+}
+
+func (s *Server) Handle(name string) string {
+	name = strings.TrimSpace(name)
+	if err := validate(name); err != nil {
+		return ""
+	}
+
+	defer store.Close()
+
+	return s.Store.Get(name)
+}
+
+func validate(name string) error {
+	return nil
+}
+`,
+	"store/store.go": `package store
+
+type Store interface {
+	Get(key string) string
+}
+
+type Memory struct{}
+
+func (Memory) Get(key string) string { return key }
+
+type Disk struct{}
+
+func (*Disk) Get(key string) string { return load(key) }
+
+func load(key string) string { return key }
+
+func Close() { go flush() }
+
+func flush() {}
+`,
+}
+
+func Test_Sequence(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range sequenceModule {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	pkgs, err := godiagram.LoadPackages(dir, []string{"./..."})
+	require.Nil(t, err)
+
+	expected := `@startuml
+participant "example.com/app/server" as example_com_app_server
+participant "example.com/app/store" as example_com_app_store
+activate example_com_app_server
+example_com_app_server -> example_com_app_server : validate()
+activate example_com_app_server
+example_com_app_server --> example_com_app_server
+deactivate example_com_app_server
+example_com_app_server -> example_com_app_store : defer Close()
+activate example_com_app_store
+example_com_app_store -> example_com_app_store : go flush()
+example_com_app_store --> example_com_app_server
+deactivate example_com_app_store
+example_com_app_server -> example_com_app_store : Get()
+note right of example_com_app_store
+  implemented by:
+  * store.Disk
+  * store.Memory
+end note
+activate example_com_app_store
+example_com_app_store --> example_com_app_server
+deactivate example_com_app_store
+deactivate example_com_app_server
+@enduml
+`
+
+	actual, err := godiagram.Sequence(pkgs, godiagram.SequenceOptions{Entry: "example.com/app/server.Server.Handle"})
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(actual))
+
+	actual, err = godiagram.Sequence(pkgs, godiagram.SequenceOptions{
+		Entry:   "example.com/app/store.Disk.Get",
+		ByType:  true,
+		Include: []*regexp.Regexp{regexp.MustCompile(`example\.com`)},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, `@startuml
+participant "store.Disk" as example_com_app_store_Disk
+participant "example.com/app/store" as example_com_app_store
+activate example_com_app_store_Disk
+example_com_app_store_Disk -> example_com_app_store : load()
+activate example_com_app_store
+example_com_app_store --> example_com_app_store_Disk
+deactivate example_com_app_store
+deactivate example_com_app_store_Disk
+@enduml
+`, string(actual))
+
+	_, err = godiagram.Sequence(pkgs, godiagram.SequenceOptions{Entry: "example.com/app/server.Missing"})
+	assert.NotNil(t, err)
+
+	_, err = godiagram.Sequence(pkgs, godiagram.SequenceOptions{Entry: "server"})
+	assert.NotNil(t, err)
+}
+
+func Test_SequenceDiamond(t *testing.T) {
+	dir := t.TempDir()
+
+	module := map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.20\n",
+		// The last element of the package path contains dots.
+		"yaml.v3/yaml.go": `package yaml
+
+func Marshal() {
+	left()
+	right()
+}
+
+func left() { shared() }
+
+func right() { shared() }
+
+func shared() { leaf() }
+
+func leaf() {}
+`,
+	}
+
+	for name, content := range module {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	pkgs, err := godiagram.LoadPackages(dir, []string{"./..."})
+	require.Nil(t, err)
+
+	actual, err := godiagram.Sequence(pkgs, godiagram.SequenceOptions{Entry: "example.com/app/yaml.v3.Marshal"})
+	require.Nil(t, err)
+
+	// The calls of shared are drawn once, the second call refers to them.
+	assert.Equal(t, `@startuml
+participant "example.com/app/yaml.v3" as example_com_app_yaml_v3
+activate example_com_app_yaml_v3
+example_com_app_yaml_v3 -> example_com_app_yaml_v3 : left()
+activate example_com_app_yaml_v3
+example_com_app_yaml_v3 -> example_com_app_yaml_v3 : shared()
+activate example_com_app_yaml_v3
+example_com_app_yaml_v3 -> example_com_app_yaml_v3 : leaf()
+activate example_com_app_yaml_v3
+example_com_app_yaml_v3 --> example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+example_com_app_yaml_v3 --> example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+example_com_app_yaml_v3 --> example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+example_com_app_yaml_v3 -> example_com_app_yaml_v3 : right()
+activate example_com_app_yaml_v3
+example_com_app_yaml_v3 -> example_com_app_yaml_v3 : shared()
+activate example_com_app_yaml_v3
+ref over example_com_app_yaml_v3 : shared(), drawn above
+example_com_app_yaml_v3 --> example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+example_com_app_yaml_v3 --> example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+deactivate example_com_app_yaml_v3
+@enduml
+`, string(actual))
+
+	_, err = godiagram.Sequence(pkgs, godiagram.SequenceOptions{Entry: "example.com/app/yaml.v3.Missing"})
+	assert.EqualError(t, err, "function not found: [example.com/app/yaml.v3.Missing]")
+}
@@ -0,0 +1,205 @@
+// Package lint checks Plant UML files against a set of rules.
+//
+// A finding can be ignored by adding a comment on the line before it:
+//
+//	' gopuml:ignore hardcoded-color
+//	Bob -> Alice #red : hello
+//
+// Several rules can be listed separated by commas,
+// when no rule is listed, all findings on the next line are ignored.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+)
+
+// Rule is a lint rule.
+type Rule struct {
+	Name        string
+	Description string
+	// Enabled is true when the rule is enabled by default.
+	Enabled bool
+	// Check checks the file and reports problems.
+	Check func(cfg Config, f File, report Reporter)
+}
+
+// Reporter reports a problem on a line, starting at 1.
+type Reporter func(line int, message string)
+
+var registry = make(map[string]Rule)
+
+// Register registers a rule, it panics if a rule with the same name already is registered.
+func Register(rule Rule) {
+	if _, ok := registry[rule.Name]; ok {
+		panic(fmt.Sprintf("lint rule already registered: [%s]", rule.Name))
+	}
+
+	registry[rule.Name] = rule
+}
+
+// Rules returns all registered rules sorted by name.
+func Rules() []Rule {
+	rules := make([]Rule, 0, len(registry))
+
+	for _, rule := range registry {
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+
+	return rules
+}
+
+// Config configures which rules to run and the options of the rules.
+type Config struct {
+	// Rules enables or disables rules by name,
+	// rules which aren't listed use their default.
+	Rules map[string]bool
+
+	// MaxParticipants is the maximum number of participants in a sequence diagram.
+	MaxParticipants int
+	// Skin is the file every diagram should include.
+	Skin string
+}
+
+// DefaultMaxParticipants is the default maximum number of participants in a sequence diagram.
+const DefaultMaxParticipants = 10
+
+// DefaultConfig returns a Config with the default options.
+func DefaultConfig() Config {
+	return Config{
+		Rules:           make(map[string]bool),
+		MaxParticipants: DefaultMaxParticipants,
+	}
+}
+
+// EnabledRules returns the enabled rules, or an error if the config refers to an unknown rule.
+func (cfg Config) EnabledRules() ([]Rule, error) {
+	for name := range cfg.Rules {
+		if _, ok := registry[name]; !ok {
+			return nil, fmt.Errorf("unknown lint rule: [%s]", name)
+		}
+	}
+
+	var rules []Rule
+
+	for _, rule := range Rules() {
+		enabled, ok := cfg.Rules[rule.Name]
+		if !ok {
+			enabled = rule.Enabled
+		}
+
+		if enabled {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// Line is a line in a File.
+type Line struct {
+	Text string
+	// IsComment is true if the line is a comment or part of a block comment.
+	IsComment bool
+}
+
+// File is a Plant UML file to lint.
+type File struct {
+	Path  string
+	Lines []Line
+}
+
+// NewFile splits the content into lines and marks the comments.
+func NewFile(path string, content []byte) File {
+	f := File{Path: path}
+
+	inBlockComment := false
+
+	for _, text := range strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(text)
+
+		line := Line{Text: text}
+
+		switch {
+		case inBlockComment:
+			line.IsComment = true
+			inBlockComment = !strings.HasSuffix(trimmed, "'/")
+		case strings.HasPrefix(trimmed, "/'"):
+			line.IsComment = true
+			inBlockComment = !strings.HasSuffix(trimmed, "'/")
+		case strings.HasPrefix(trimmed, "'"):
+			line.IsComment = true
+		}
+
+		f.Lines = append(f.Lines, line)
+	}
+
+	return f
+}
+
+var ignoreRegexp = regexp.MustCompile(`^\s*'\s*gopuml:ignore\b(.*)$`)
+
+// ignored returns the rules ignored on each line,
+// an empty set of rules means that all rules are ignored.
+func (f File) ignored() map[int]map[string]bool {
+	ignored := make(map[int]map[string]bool)
+
+	for idx, line := range f.Lines {
+		matches := ignoreRegexp.FindStringSubmatch(line.Text)
+		if matches == nil {
+			continue
+		}
+
+		rules := make(map[string]bool)
+
+		for _, name := range strings.Split(matches[1], ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				rules[name] = true
+			}
+		}
+
+		// Lines start at 1, the ignore comment applies to the next line.
+		ignored[idx+2] = rules
+	}
+
+	return ignored
+}
+
+// Lint runs the enabled rules on the file.
+func Lint(cfg Config, f File) ([]diagnostic.Diagnostic, error) {
+	rules, err := cfg.EnabledRules()
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := f.ignored()
+
+	var diagnostics []diagnostic.Diagnostic
+
+	for _, rule := range rules {
+		name := rule.Name
+
+		rule.Check(cfg, f, func(line int, message string) {
+			if rules, ok := ignored[line]; ok && (len(rules) == 0 || rules[name]) {
+				return
+			}
+
+			diagnostics = append(diagnostics, diagnostic.Diagnostic{
+				File:    f.Path,
+				Line:    line,
+				Message: message,
+				Rule:    name,
+			})
+		})
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool { return diagnostics[i].Line < diagnostics[j].Line })
+
+	return diagnostics, nil
+}
@@ -0,0 +1,166 @@
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+	"github.com/lonnblad/gopuml/internal/lint"
+)
+
+func Test_Lint(t *testing.T) {
+	testcases := []struct {
+		name           string
+		content        string
+		configure      func(cfg *lint.Config)
+		expectedResult []diagnostic.Diagnostic
+	}{
+		{
+			name:    "valid",
+			content: "@startuml Example\nBob -> Alice : hello\n@enduml",
+		},
+		{
+			name:    "diagram name",
+			content: "@startuml\nBob -> Alice : hello\n@enduml",
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 1, Message: "diagram has no name after @startuml", Rule: lint.RuleDiagramName},
+			},
+		},
+		{
+			name:    "remote include",
+			content: "@startuml Example\n!include https://example.com/skin.puml\n!include <C4/C4_Container>\n@enduml",
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 2, Message: "include of remote URL: https://example.com/skin.puml", Rule: lint.RuleRemoteInclude},
+			},
+		},
+		{
+			name:      "skin include",
+			content:   "@startuml Example\n!include skin.puml\n@enduml\n@startuml Other\n@enduml",
+			configure: func(cfg *lint.Config) { cfg.Skin = "skin.puml"; cfg.Rules[lint.RuleSkinInclude] = true },
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 4, Message: "diagram doesn't include the skin: skin.puml", Rule: lint.RuleSkinInclude},
+			},
+		},
+		{
+			name:    "enduml",
+			content: "@startuml Example\nBob -> Alice : hello\n",
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 1, Message: "@startuml has no matching @enduml", Rule: lint.RuleEndUML},
+			},
+		},
+		{
+			name:      "max participants",
+			content:   "@startuml Example\nparticipant \"The Server\" as S\nactor Bob\nBob -> Alice : hello\nAlice --> S\n@enduml",
+			configure: func(cfg *lint.Config) { cfg.MaxParticipants = 2 },
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 1, Message: "sequence diagram has 3 participants, the maximum is 2", Rule: lint.RuleMaxParticipants},
+			},
+		},
+		{
+			name:      "max participants in class diagram",
+			content:   "@startuml Example\nclass A\nA --> B\nB --> C\n@enduml",
+			configure: func(cfg *lint.Config) { cfg.MaxParticipants = 2 },
+		},
+		{
+			name:    "hardcoded color",
+			content: "@startuml Example\n' Bob #red\nBob -> Alice #LightBlue : hello\nAlice -[#00ff00]> Bob\n@enduml",
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 3, Message: "hard-coded color: #LightBlue", Rule: lint.RuleHardcodedColor},
+				{Line: 4, Message: "hard-coded color: #00ff00", Rule: lint.RuleHardcodedColor},
+			},
+		},
+		{
+			name:      "disabled rule",
+			content:   "@startuml\n@enduml",
+			configure: func(cfg *lint.Config) { cfg.Rules[lint.RuleDiagramName] = false },
+		},
+		{
+			name: "ignore comments",
+			content: "' gopuml:ignore diagram-name\n@startuml\n' gopuml:ignore\nBob -> Alice #red : hello\n" +
+				"' gopuml:ignore enduml\nAlice -> Bob #blue : hi\n@enduml",
+			expectedResult: []diagnostic.Diagnostic{
+				{Line: 6, Message: "hard-coded color: #blue", Rule: lint.RuleHardcodedColor},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		cfg := lint.DefaultConfig()
+		if tc.configure != nil {
+			tc.configure(&cfg)
+		}
+
+		for idx := range tc.expectedResult {
+			tc.expectedResult[idx].File = "test.puml"
+		}
+
+		actualResult, err := lint.Lint(cfg, lint.NewFile("test.puml", []byte(tc.content)))
+		require.Nil(t, err, tc.name)
+		assert.Equal(t, tc.expectedResult, actualResult, tc.name)
+	}
+}
+
+func Test_LintUnknownRule(t *testing.T) {
+	cfg := lint.DefaultConfig()
+	cfg.Rules["unknown"] = true
+
+	_, err := lint.Lint(cfg, lint.NewFile("test.puml", nil))
+	assert.NotNil(t, err)
+}
+
+func Test_WriteSARIF(t *testing.T) {
+	rules := []lint.Rule{{Name: lint.RuleDiagramName, Description: "every diagram has a name after @startuml"}}
+	diagnostics := []diagnostic.Diagnostic{
+		{File: "test.puml", Line: 1, Message: "diagram has no name after @startuml", Rule: lint.RuleDiagramName},
+	}
+
+	var out bytes.Buffer
+
+	err := lint.WriteSARIF(&out, rules, diagnostics)
+	require.Nil(t, err)
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+
+	err = json.Unmarshal(out.Bytes(), &log)
+	require.Nil(t, err)
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, "gopuml", log.Runs[0].Tool.Driver.Name)
+	require.Len(t, log.Runs[0].Tool.Driver.Rules, 1)
+	assert.Equal(t, lint.RuleDiagramName, log.Runs[0].Tool.Driver.Rules[0].ID)
+	require.Len(t, log.Runs[0].Results, 1)
+	assert.Equal(t, lint.RuleDiagramName, log.Runs[0].Results[0].RuleID)
+	require.Len(t, log.Runs[0].Results[0].Locations, 1)
+	assert.Equal(t, "test.puml", log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
@@ -0,0 +1,321 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The names of the built-in rules.
+const (
+	RuleDiagramName     = "diagram-name"
+	RuleRemoteInclude   = "remote-include"
+	RuleSkinInclude     = "skin-include"
+	RuleEndUML          = "enduml"
+	RuleMaxParticipants = "max-participants"
+	RuleHardcodedColor  = "hardcoded-color"
+)
+
+func init() { // nolint: gochecknoinits
+	Register(Rule{
+		Name:        RuleDiagramName,
+		Description: "every diagram has a name after @startuml",
+		Enabled:     true,
+		Check:       checkDiagramName,
+	})
+	Register(Rule{
+		Name:        RuleRemoteInclude,
+		Description: "no !include of remote URLs",
+		Enabled:     true,
+		Check:       checkRemoteInclude,
+	})
+	Register(Rule{
+		Name:        RuleSkinInclude,
+		Description: "every diagram includes the configured skin",
+		Enabled:     false,
+		Check:       checkSkinInclude,
+	})
+	Register(Rule{
+		Name:        RuleEndUML,
+		Description: "every @startuml has a matching @enduml",
+		Enabled:     true,
+		Check:       checkEndUML,
+	})
+	Register(Rule{
+		Name:        RuleMaxParticipants,
+		Description: "sequence diagrams have at most the configured number of participants",
+		Enabled:     true,
+		Check:       checkMaxParticipants,
+	})
+	Register(Rule{
+		Name:        RuleHardcodedColor,
+		Description: "no hard-coded colors, colors should come from the skin",
+		Enabled:     true,
+		Check:       checkHardcodedColor,
+	})
+}
+
+var (
+	startRegexp = regexp.MustCompile(`^\s*@start(\w+)\s*(.*)$`)
+	endRegexp   = regexp.MustCompile(`^\s*@end(\w+)\b`)
+)
+
+// diagram is a diagram in a file, a file can contain several diagrams.
+type diagram struct {
+	Kind string
+	Name string
+	// Start and End are the lines of @start and @end, End is 0 if it's missing.
+	Start, End int
+	Lines      []Line
+}
+
+func (f File) diagrams() []diagram {
+	var diagrams []diagram
+
+	var current *diagram
+
+	for idx, line := range f.Lines {
+		if line.IsComment {
+			if current != nil {
+				current.Lines = append(current.Lines, line)
+			}
+
+			continue
+		}
+
+		if matches := startRegexp.FindStringSubmatch(line.Text); matches != nil {
+			if current != nil {
+				diagrams = append(diagrams, *current)
+			}
+
+			current = &diagram{Kind: matches[1], Name: strings.TrimSpace(matches[2]), Start: idx + 1}
+
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if matches := endRegexp.FindStringSubmatch(line.Text); matches != nil && matches[1] == current.Kind {
+			current.End = idx + 1
+			diagrams = append(diagrams, *current)
+			current = nil
+
+			continue
+		}
+
+		current.Lines = append(current.Lines, line)
+	}
+
+	if current != nil {
+		diagrams = append(diagrams, *current)
+	}
+
+	return diagrams
+}
+
+func checkDiagramName(_ Config, f File, report Reporter) {
+	for _, d := range f.diagrams() {
+		if d.Name == "" {
+			report(d.Start, fmt.Sprintf("diagram has no name after @start%s", d.Kind))
+		}
+	}
+}
+
+var includeRegexp = regexp.MustCompile(`^\s*!include(?:url|_many|_once|sub)?\s+(.+?)\s*$`)
+
+func includes(f File) map[int]string {
+	includes := make(map[int]string)
+
+	for idx, line := range f.Lines {
+		if line.IsComment {
+			continue
+		}
+
+		if matches := includeRegexp.FindStringSubmatch(line.Text); matches != nil {
+			includes[idx+1] = strings.Trim(matches[1], `"`)
+		}
+	}
+
+	return includes
+}
+
+func isRemote(target string) bool {
+	return strings.Contains(target, "://")
+}
+
+func checkRemoteInclude(_ Config, f File, report Reporter) {
+	for line, target := range includes(f) {
+		if isRemote(target) {
+			report(line, fmt.Sprintf("include of remote URL: %s", target))
+		}
+	}
+}
+
+func checkSkinInclude(cfg Config, f File, report Reporter) {
+	if cfg.Skin == "" {
+		return
+	}
+
+	skin := resolveInclude(".", cfg.Skin)
+	includes := includes(f)
+
+	for _, d := range f.diagrams() {
+		found := false
+
+		end := d.End
+		if end == 0 {
+			end = len(f.Lines)
+		}
+
+		for line, target := range includes {
+			if line > d.Start && line < end && resolveInclude(filepath.Dir(f.Path), target) == skin {
+				found = true
+			}
+		}
+
+		if !found {
+			report(d.Start, fmt.Sprintf("diagram doesn't include the skin: %s", cfg.Skin))
+		}
+	}
+}
+
+func resolveInclude(dir, target string) string {
+	if isRemote(target) || strings.HasPrefix(target, "<") {
+		return target
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+
+	if abs, err := filepath.Abs(target); err == nil {
+		return abs
+	}
+
+	return filepath.Clean(target)
+}
+
+func checkEndUML(_ Config, f File, report Reporter) {
+	for _, d := range f.diagrams() {
+		if d.End == 0 {
+			report(d.Start, fmt.Sprintf("@start%s has no matching @end%s", d.Kind, d.Kind))
+		}
+	}
+}
+
+var (
+	participantRegexp = regexp.MustCompile(
+		`^\s*(?:participant|actor|boundary|control|entity|database|collections|queue)\s+` +
+			`(?:"([^"]+)"|(\S+))(?:\s+as\s+(\S+))?`,
+	)
+	messageRegexp = regexp.MustCompile(
+		`^\s*("[^"]+"|[\w.]+)\s*([<ox\\/]*[-.]+(?:\[[^\]]*\][-.]*)?[>ox\\/]*)\s*("[^"]+"|[\w.]+)\s*(?::.*)?$`,
+	)
+	nonSequenceRegexp = regexp.MustCompile(
+		`^\s*(?:class|interface|enum|abstract|annotation|usecase|component|node|state|object|start|stop)\b|^\s*:.*;\s*$`,
+	)
+)
+
+func isSequenceDiagram(d diagram) bool {
+	if d.Kind != "uml" {
+		return false
+	}
+
+	isSequence := false
+
+	for _, line := range d.Lines {
+		if line.IsComment {
+			continue
+		}
+
+		if nonSequenceRegexp.MatchString(line.Text) {
+			return false
+		}
+
+		if participantRegexp.MatchString(line.Text) {
+			isSequence = true
+		}
+
+		if _, _, ok := parseMessage(line.Text); ok {
+			isSequence = true
+		}
+	}
+
+	return isSequence
+}
+
+// parseMessage parses a message between two participants, like "Bob -> Alice : hello".
+func parseMessage(text string) (from, to string, ok bool) {
+	matches := messageRegexp.FindStringSubmatch(text)
+	if matches == nil || !strings.ContainsAny(matches[2], `<>\/`) {
+		return "", "", false
+	}
+
+	return strings.Trim(matches[1], `"`), strings.Trim(matches[3], `"`), true
+}
+
+func checkMaxParticipants(cfg Config, f File, report Reporter) {
+	if cfg.MaxParticipants <= 0 {
+		return
+	}
+
+	for _, d := range f.diagrams() {
+		if !isSequenceDiagram(d) {
+			continue
+		}
+
+		participants := make(map[string]bool)
+
+		for _, line := range d.Lines {
+			if line.IsComment {
+				continue
+			}
+
+			if matches := participantRegexp.FindStringSubmatch(line.Text); matches != nil {
+				switch {
+				case matches[3] != "":
+					participants[matches[3]] = true
+				case matches[1] != "":
+					participants[matches[1]] = true
+				default:
+					participants[matches[2]] = true
+				}
+
+				continue
+			}
+
+			if from, to, ok := parseMessage(line.Text); ok {
+				participants[from] = true
+				participants[to] = true
+			}
+		}
+
+		if len(participants) > cfg.MaxParticipants {
+			report(d.Start, fmt.Sprintf("sequence diagram has %d participants, the maximum is %d", len(participants), cfg.MaxParticipants))
+		}
+	}
+}
+
+var colorRegexp = regexp.MustCompile(`#(?:[0-9a-fA-F]{6}|[0-9a-fA-F]{3})\b|#(?i:` + strings.Join(namedColors, "|") + `)\b`)
+
+var namedColors = []string{
+	"aqua", "black", "blue", "brown", "cyan", "darkblue", "darkgray", "darkgreen", "darkgrey", "darkorange",
+	"darkred", "gold", "gray", "green", "grey", "indigo", "lightblue", "lightgray", "lightgreen", "lightgrey",
+	"lightyellow", "lime", "magenta", "maroon", "navy", "olive", "orange", "pink", "purple", "red",
+	"salmon", "silver", "teal", "violet", "white", "yellow",
+}
+
+func checkHardcodedColor(_ Config, f File, report Reporter) {
+	for idx, line := range f.Lines {
+		if line.IsComment || includeRegexp.MatchString(line.Text) {
+			continue
+		}
+
+		if color := colorRegexp.FindString(line.Text); color != "" {
+			report(idx+1, fmt.Sprintf("hard-coded color: %s", color))
+		}
+	}
+}
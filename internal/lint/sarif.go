@@ -0,0 +1,122 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/lonnblad/gopuml/internal/diagnostic"
+)
+
+// FormatSARIF writes the diagnostics as a SARIF log.
+const FormatSARIF = "sarif"
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifLevel   = "warning"
+
+	toolName           = "gopuml"
+	toolInformationURI = "https://github.com/lonnblad/gopuml"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes the diagnostics found by the rules as a SARIF log.
+func WriteSARIF(out io.Writer, rules []Rule, diagnostics []diagnostic.Diagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolInformationURI,
+			Rules:          make([]sarifRule, 0, len(rules)),
+		}},
+		Results: make([]sarifResult, 0, len(diagnostics)),
+	}
+
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               rule.Name,
+			ShortDescription: sarifMessage{Text: rule.Description},
+		})
+	}
+
+	for _, d := range diagnostics {
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(d.File)},
+		}}
+
+		if d.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line}
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    d.Rule,
+			Level:     sarifLevel,
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	log := sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}}
+	if err := encoder.Encode(log); err != nil {
+		return fmt.Errorf("couldn't write SARIF log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+// Package parser parses Plant UML source into lines with their kind and nesting depth.
+//
+// The parser doesn't validate the Plant UML, it recognizes the structure needed
+// to format and analyze the source, like blocks and messages, while anything
+// else is kept as a statement.
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind is the kind of a line.
+type Kind int
+
+const (
+	// KindBlank is an empty line.
+	KindBlank Kind = iota
+	// KindComment is a comment or a line in a block comment.
+	KindComment
+	// KindStart is the start of a diagram, like @startuml.
+	KindStart
+	// KindEnd is the end of a diagram, like @enduml.
+	KindEnd
+	// KindOpen opens a block, like alt, if or a line ending with {.
+	KindOpen
+	// KindElse separates the sections of a block, like else.
+	KindElse
+	// KindClose closes a block, like end, endif or }.
+	KindClose
+	// KindMessage is an arrow between two participants.
+	KindMessage
+	// KindText is a line in a note, legend or multi-line action, the text is free form.
+	KindText
+	// KindStatement is any other line.
+	KindStatement
+)
+
+// Line is a parsed line.
+type Line struct {
+	Kind Kind
+	// Number is the line number, starting at 1.
+	Number int
+	// Text is the line without the line ending.
+	Text string
+	// Depth is the nesting depth of the line, the lines which open, separate
+	// and close a block have the depth of the block itself.
+	Depth int
+	// Message is set when the kind is KindMessage.
+	Message *Message
+	// Brace is true when the line opens a block with a brace, like "package Foo {",
+	// rather than with a keyword, like "alt".
+	Brace bool
+}
+
+// Content returns the text of the line without surrounding whitespace.
+func (l Line) Content() string {
+	return strings.TrimSpace(l.Text)
+}
+
+// Message is an arrow between two participants, like "Bob -> Alice : hello".
+type Message struct {
+	From  string
+	Arrow string
+	To    string
+	// Label is the text after the colon, HasLabel is true if the message has a colon.
+	Label    string
+	HasLabel bool
+}
+
+// block is an open block.
+type block struct {
+	closers []string
+	elses   []string
+	// isText is true when the lines in the block are free form text.
+	isText bool
+	// brace is true when the block is opened with a brace.
+	brace bool
+}
+
+var (
+	startRegexp        = regexp.MustCompile(`(?i)^@start\w+`)
+	endRegexp          = regexp.MustCompile(`(?i)^@end\w+`)
+	groupRegexp        = regexp.MustCompile(`(?i)^(alt|opt|loop|par|break|critical|group)(\s|$)`)
+	boxRegexp          = regexp.MustCompile(`(?i)^box(\s|$)`)
+	ifRegexp           = regexp.MustCompile(`(?i)^if\s*\(.*\bthen\b`)
+	whileRegexp        = regexp.MustCompile(`(?i)^while\s*\(`)
+	repeatRegexp       = regexp.MustCompile(`(?i)^repeat(\s*$|\s*:)`)
+	switchRegexp       = regexp.MustCompile(`(?i)^switch\s*\(`)
+	noteRegexp         = regexp.MustCompile(`(?i)^[hr]?note(\s|$)`)
+	noteAsRegexp       = regexp.MustCompile(`(?i)^[hr]?note\s+".*"\s+as\s+\S+$`)
+	refRegexp          = regexp.MustCompile(`(?i)^ref\s+over\s`)
+	textBlockRegexp    = regexp.MustCompile(`(?i)^(legend(\s+\w+)*|title|header|footer|caption)$`)
+	actionStartRegexp  = regexp.MustCompile(`^:`)
+	actionEndRegexp    = regexp.MustCompile(`[;|<>/\\\]}]$`)
+	blockCommentRegexp = regexp.MustCompile(`^/'`)
+)
+
+var (
+	groupClosers  = []string{"end"}
+	groupElses    = []string{"else"}
+	boxClosers    = []string{"end box"}
+	ifClosers     = []string{"endif", "end if"}
+	ifElses       = []string{"elseif", "else if", "else"}
+	whileClosers  = []string{"endwhile", "end while"}
+	repeatClosers = []string{"repeat while", "repeatwhile"}
+	forkClosers   = []string{"end fork", "endfork", "end merge"}
+	forkElses     = []string{"fork again"}
+	splitClosers  = []string{"end split", "endsplit"}
+	splitElses    = []string{"split again"}
+	switchClosers = []string{"endswitch", "end switch"}
+	switchElses   = []string{"case"}
+	braceClosers  = []string{"}"}
+	noteClosers   = []string{"end note", "endnote", "end hnote", "endhnote", "end rnote", "endrnote"}
+	refClosers    = []string{"end ref", "endref"}
+)
+
+// Parse parses the content into lines.
+func Parse(content []byte) []Line {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+
+	var (
+		lines          []Line
+		stack          []block
+		inBlockComment bool
+		inAction       bool
+	)
+
+	for idx, raw := range strings.Split(text, "\n") {
+		line := Line{Number: idx + 1, Text: raw, Depth: len(stack)}
+		content := line.Content()
+		lower := strings.ToLower(content)
+
+		switch {
+		case inBlockComment:
+			line.Kind = KindComment
+			inBlockComment = !strings.HasSuffix(content, "'/")
+		case inAction:
+			line.Kind = KindText
+			inAction = !actionEndRegexp.MatchString(content)
+		case len(stack) > 0 && stack[len(stack)-1].isText:
+			if hasPrefix(lower, stack[len(stack)-1].closers) {
+				stack = stack[:len(stack)-1]
+				line.Kind, line.Depth = KindClose, len(stack)
+			} else {
+				line.Kind = KindText
+			}
+		case content == "":
+			line.Kind = KindBlank
+		case strings.HasPrefix(content, "'"):
+			line.Kind = KindComment
+		case blockCommentRegexp.MatchString(content):
+			line.Kind = KindComment
+			inBlockComment = !strings.HasSuffix(content, "'/") || len(content) < 4
+		case startRegexp.MatchString(content):
+			line.Kind, line.Depth = KindStart, 0
+			stack = nil
+		case endRegexp.MatchString(content):
+			line.Kind, line.Depth = KindEnd, 0
+			stack = nil
+		default:
+			var open *block
+
+			line.Kind, open, stack = parseStatement(content, lower, stack)
+
+			if line.Kind == KindClose {
+				line.Depth = len(stack)
+			}
+
+			if line.Kind == KindElse {
+				stack = stack[:len(stack)-1]
+				line.Depth = len(stack)
+			}
+
+			if open != nil {
+				stack = append(stack, *open)
+				line.Brace = open.brace
+			}
+
+			if line.Kind == KindStatement && actionStartRegexp.MatchString(content) {
+				inAction = !actionEndRegexp.MatchString(content)
+			}
+
+			if line.Kind == KindStatement {
+				line.Message = parseMessage(content)
+				if line.Message != nil {
+					line.Kind = KindMessage
+				}
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// parseStatement finds the kind of the line and the block it opens, if any.
+// For KindElse, the returned block replaces the top of the stack.
+func parseStatement(content, lower string, stack []block) (Kind, *block, []block) {
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+
+		if hasPrefix(lower, top.closers) {
+			return KindClose, nil, stack[:len(stack)-1]
+		}
+
+		if hasPrefix(lower, top.elses) {
+			next := top
+			return KindElse, &next, stack
+		}
+	}
+
+	switch {
+	case groupRegexp.MatchString(content):
+		return KindOpen, &block{closers: groupClosers, elses: groupElses}, stack
+	case boxRegexp.MatchString(content):
+		return KindOpen, &block{closers: boxClosers}, stack
+	case ifRegexp.MatchString(content):
+		return KindOpen, &block{closers: ifClosers, elses: ifElses}, stack
+	case whileRegexp.MatchString(content):
+		return KindOpen, &block{closers: whileClosers}, stack
+	case repeatRegexp.MatchString(content):
+		return KindOpen, &block{closers: repeatClosers}, stack
+	case lower == "fork":
+		return KindOpen, &block{closers: forkClosers, elses: forkElses}, stack
+	case lower == "split":
+		return KindOpen, &block{closers: splitClosers, elses: splitElses}, stack
+	case switchRegexp.MatchString(content):
+		return KindOpen, &block{closers: switchClosers, elses: switchElses}, stack
+	case noteRegexp.MatchString(content) && !strings.Contains(content, ":") && !noteAsRegexp.MatchString(content):
+		return KindOpen, &block{closers: noteClosers, isText: true}, stack
+	case refRegexp.MatchString(content) && !strings.Contains(content, ":"):
+		return KindOpen, &block{closers: refClosers, isText: true}, stack
+	case textBlockRegexp.MatchString(content):
+		first := strings.Fields(lower)[0]
+		return KindOpen, &block{closers: []string{"end " + first, "end" + first}, isText: true}, stack
+	case strings.HasSuffix(content, "{") && !strings.Contains(content, "}"):
+		return KindOpen, &block{closers: braceClosers, brace: true}, stack
+	}
+
+	return KindStatement, nil, stack
+}
+
+// hasPrefix returns true if the lower case content starts with any of the keywords as a word.
+func hasPrefix(lower string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if !strings.HasPrefix(lower, keyword) {
+			continue
+		}
+
+		rest := lower[len(keyword):]
+		if rest == "" || keyword == "}" || !isWordChar(rest[0]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+var messageRegexp = regexp.MustCompile(
+	`^(\[|\]|"[^"]+"|\([^)]+\)|[\w.$@]+)\s*` +
+		`([<ox*|\\/}+#]*(?:[-.=]+)(?:\[[^\]]*\][-.=]*)?(?:(?:left|right|up|down|l|r|u|d)[-.=]+)?[>ox*|\\/{+#]*)\s*` +
+		`(\[|\]|"[^"]+"|\([^)]+\)|[\w.$@]+)\s*` +
+		`(?:(:)\s*(.*))?$`,
+)
+
+// parseMessage parses a message like "Bob -> Alice : hello", it returns nil if the content isn't a message.
+func parseMessage(content string) *Message {
+	matches := messageRegexp.FindStringSubmatch(content)
+	if matches == nil || len(matches[2]) < 2 {
+		return nil
+	}
+
+	return &Message{
+		From:     matches[1],
+		Arrow:    matches[2],
+		To:       matches[3],
+		HasLabel: matches[4] != "",
+		Label:    strings.TrimSpace(matches[5]),
+	}
+}
@@ -0,0 +1,79 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/parser"
+)
+
+func Test_Parse(t *testing.T) {
+	content := `@startuml Example
+' comment
+alt success
+  Bob -> "Alice" : hello
+else
+  note over Bob
+    end
+  end note
+end
+package Endpoint {
+}
+@enduml`
+
+	expected := []struct {
+		kind  parser.Kind
+		depth int
+		brace bool
+	}{
+		{kind: parser.KindStart, depth: 0},
+		{kind: parser.KindComment, depth: 0},
+		{kind: parser.KindOpen, depth: 0},
+		{kind: parser.KindMessage, depth: 1},
+		{kind: parser.KindElse, depth: 0},
+		{kind: parser.KindOpen, depth: 1},
+		{kind: parser.KindText, depth: 2},
+		{kind: parser.KindClose, depth: 1},
+		{kind: parser.KindClose, depth: 0},
+		{kind: parser.KindOpen, depth: 0, brace: true},
+		{kind: parser.KindClose, depth: 0},
+		{kind: parser.KindEnd, depth: 0},
+	}
+
+	lines := parser.Parse([]byte(content))
+	require.Len(t, lines, len(expected))
+
+	for idx, line := range lines {
+		assert.Equal(t, idx+1, line.Number)
+		assert.Equal(t, expected[idx].kind, line.Kind, line.Text)
+		assert.Equal(t, expected[idx].depth, line.Depth, line.Text)
+		assert.Equal(t, expected[idx].brace, line.Brace, line.Text)
+	}
+
+	expectedMessage := &parser.Message{From: "Bob", Arrow: "->", To: `"Alice"`, Label: "hello", HasLabel: true}
+	assert.Equal(t, expectedMessage, lines[3].Message)
+}
+
+func Test_ParseMessages(t *testing.T) {
+	testcases := []struct {
+		content  string
+		expected *parser.Message
+	}{
+		{content: "Bob->Alice", expected: &parser.Message{From: "Bob", Arrow: "->", To: "Alice"}},
+		{content: "Bob -[#red]> Alice : hi", expected: &parser.Message{From: "Bob", Arrow: "-[#red]>", To: "Alice", Label: "hi", HasLabel: true}},
+		{content: "A <|-- B", expected: &parser.Message{From: "A", Arrow: "<|--", To: "B"}},
+		{content: "(Use) ..> (Case)", expected: &parser.Message{From: "(Use)", Arrow: "..>", To: "(Case)"}},
+		{content: "A - B"},
+		{content: "participant Bob"},
+		{content: "-> label;"},
+		{content: `A "1" *-- "many" B`},
+	}
+
+	for _, tc := range testcases {
+		lines := parser.Parse([]byte(tc.content))
+		require.Len(t, lines, 1)
+		assert.Equal(t, tc.expected, lines[0].Message, tc.content)
+	}
+}
@@ -0,0 +1,133 @@
+package render
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// ClientOptions configures the HTTP client and the headers used for the requests to the Plant UML server,
+// like for a server behind an authenticating proxy.
+type ClientOptions struct {
+	// Header is added to every request.
+	Header http.Header
+	// Token is sent as a bearer token in the Authorization header.
+	Token string
+	// Username and Password are sent with basic authentication when the Username is set.
+	Username string
+	Password string
+
+	// CACertFile is a PEM file with certificates to trust in addition to the system certificates.
+	CACertFile string
+	// ClientCertFile and ClientKeyFile are PEM files with a client certificate and its key.
+	ClientCertFile string
+	ClientKeyFile  string
+
+	// Proxy is the URL of the proxy to use, the proxy of the environment is used when it's empty.
+	Proxy string
+	// Timeout is the time limit of a request, zero means no limit.
+	Timeout time.Duration
+
+	// Method is the method used to render, MethodAuto when it's empty.
+	Method string
+	// MaxLinkLength is the longest link rendered with GET when using MethodAuto,
+	// DefaultMaxLinkLength when it's zero.
+	MaxLinkLength int
+
+	// Retries is the number of times to retry a request when the server is temporarily unavailable,
+	// the backoff between the retries starts at MinBackoff and is doubled up to MaxBackoff,
+	// unless the server responds with a Retry-After header.
+	Retries    int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Rate is the max number of requests per second, zero means no limit.
+	Rate float64
+	// BreakerThreshold is the number of unavailable responses in a row which opens the circuit breaker,
+	// zero disables the breaker. The breaker stays open for the BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// NewClient creates an HTTP client configured with the TLS, proxy and timeout options.
+func NewClient(opts ClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: [%s]: %w", opts.Proxy, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport, Timeout: opts.Timeout}, nil
+}
+
+func (opts ClientOptions) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CACertFile != "" {
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the CA certificates: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in: [%s]", opts.CACertFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (opts.ClientCertFile == "") != (opts.ClientKeyFile == "") {
+		return nil, errors.New("both a client certificate and a client key are needed")
+	}
+
+	if opts.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load the client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// requestHeader returns the headers to add to every request,
+// the Authorization header is set from the token or the basic authentication.
+func (opts ClientOptions) requestHeader() http.Header {
+	header := opts.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	switch {
+	case opts.Token != "":
+		header.Set("Authorization", "Bearer "+opts.Token)
+	case opts.Username != "":
+		request := http.Request{Header: header}
+		request.SetBasicAuth(opts.Username, opts.Password)
+	}
+
+	return header
+}
@@ -0,0 +1,146 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/render"
+)
+
+func Test_NewWithOptionsTLS(t *testing.T) {
+	var received *http.Request
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req
+		w.Write([]byte("rendered")) // nolint: errcheck
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.Nil(t, os.WriteFile(caFile, caPEM, 0600))
+
+	opts := render.ClientOptions{
+		Header:         http.Header{"X-Team": []string{"docs"}},
+		Token:          "secret",
+		CACertFile:     caFile,
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+	}
+
+	renderer, err := render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", []byte("valid")))
+	assert.Equal(t, "rendered", out.String())
+	assert.Equal(t, "docs", received.Header.Get("X-Team"))
+	assert.Equal(t, "Bearer secret", received.Header.Get("Authorization"))
+	assert.Len(t, received.TLS.PeerCertificates, 1)
+
+	opts.Token = ""
+	opts.Username, opts.Password = "user", "password"
+
+	renderer, err = render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", []byte("valid")))
+
+	username, password, ok := received.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "password", password)
+
+	renderer, err = render.NewWithOptions(server.URL, render.ClientOptions{})
+	require.Nil(t, err)
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "svg", []byte("valid")))
+
+	_, err = render.NewWithOptions(server.URL, render.ClientOptions{ClientCertFile: certFile})
+	assert.NotNil(t, err)
+
+	_, err = render.NewWithOptions(server.URL, render.ClientOptions{CACertFile: keyFile})
+	assert.NotNil(t, err)
+}
+
+func Test_NewWithOptionsProxyAndTimeout(t *testing.T) {
+	var proxied string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		proxied = req.URL.String()
+		w.Write([]byte("proxied")) // nolint: errcheck
+	}))
+	defer proxy.Close()
+
+	renderer, err := render.NewWithOptions("http://plantuml.example.com", render.ClientOptions{Proxy: proxy.URL})
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+
+	require.Nil(t, renderer.Render(context.Background(), &out, "txt", []byte("valid")))
+	assert.Equal(t, "proxied", out.String())
+	assert.Equal(t, "http://plantuml.example.com/txt/valid", proxied)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	renderer, err = render.NewWithOptions(slow.URL, render.ClientOptions{Timeout: 20 * time.Millisecond})
+	require.Nil(t, err)
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "txt", []byte("valid")))
+
+	_, err = render.NewWithOptions(slow.URL, render.ClientOptions{Proxy: "://invalid"})
+	assert.NotNil(t, err)
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key to the directory.
+func writeClientCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gopuml"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+
+	require.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return certFile, keyFile
+}
@@ -0,0 +1,344 @@
+// Package render renders encoded Plant UML using a Plant UML server.
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lonnblad/gopuml"
+)
+
+const (
+	// HeaderDiagramError is the header used by the Plant UML server to report a diagram error.
+	HeaderDiagramError = "X-PlantUML-Diagram-Error"
+	// HeaderDiagramErrorLine is the header used by the Plant UML server to report the line of a diagram error.
+	HeaderDiagramErrorLine = "X-PlantUML-Diagram-Error-Line"
+)
+
+const (
+	// MethodAuto renders with GET, unless the link is longer than the max link length.
+	MethodAuto = "auto"
+	// MethodGet renders with GET requests to links.
+	MethodGet = "get"
+	// MethodPost renders with POST requests with the Plant UML as the body.
+	MethodPost = "post"
+
+	// DefaultMaxLinkLength is the longest link rendered with GET when using MethodAuto.
+	DefaultMaxLinkLength = 4000
+)
+
+// DiagramError is returned when the Plant UML server fails to render a diagram,
+// for example due to a syntax error.
+type DiagramError struct {
+	StatusCode int
+	Message    string
+	// Line is the line of the error in the rendered source, starting at 1.
+	// Line is 0 when the line is unknown.
+	Line int
+}
+
+func (err *DiagramError) Error() string {
+	if err.Line > 0 {
+		return fmt.Sprintf("line %d: %s", err.Line, err.Message)
+	}
+
+	return err.Message
+}
+
+// AsDiagramError finds the first DiagramError in the chain of err.
+func AsDiagramError(err error) (*DiagramError, bool) {
+	var diagramErr *DiagramError
+	ok := errors.As(err, &diagramErr)
+
+	return diagramErr, ok
+}
+
+// Renderer renders encoded Plant UML using a Plant UML server.
+type Renderer struct {
+	server string
+	client *http.Client
+	header http.Header
+
+	method        string
+	maxLinkLength int
+
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	limiter    *limiter
+	breaker    *breaker
+}
+
+// New creates a Renderer for the Plant UML server,
+// the provided server need to support links formatted like:
+//
+//	"<server_url>/<format>/<plant_uml_text_encoding>"
+//
+// Diagrams with links longer than the DefaultMaxLinkLength are rendered with a POST request to:
+//
+//	"<server_url>/<format>"
+func New(server string) *Renderer {
+	return &Renderer{
+		server:        strings.TrimSuffix(server, "/"),
+		client:        http.DefaultClient,
+		header:        http.Header{},
+		method:        MethodAuto,
+		maxLinkLength: DefaultMaxLinkLength,
+	}
+}
+
+// NewWithOptions creates a Renderer for the Plant UML server,
+// which uses a client configured with the options for all requests.
+func NewWithOptions(server string, opts ClientOptions) (*Renderer, error) {
+	client, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := New(server)
+	r.client = client
+	r.header = opts.requestHeader()
+
+	switch opts.Method {
+	case "":
+	case MethodAuto, MethodGet, MethodPost:
+		r.method = opts.Method
+	default:
+		return nil, fmt.Errorf("unsupported method: [%s]", opts.Method)
+	}
+
+	if opts.MaxLinkLength > 0 {
+		r.maxLinkLength = opts.MaxLinkLength
+	}
+
+	r.retries = opts.Retries
+	r.minBackoff, r.maxBackoff = DefaultMinBackoff, DefaultMaxBackoff
+
+	if opts.MinBackoff > 0 {
+		r.minBackoff = opts.MinBackoff
+	}
+
+	if opts.MaxBackoff > 0 {
+		r.maxBackoff = opts.MaxBackoff
+	}
+
+	r.limiter = newLimiter(opts.Rate)
+	r.breaker = newBreaker(opts.BreakerThreshold, opts.BreakerCooldown)
+
+	return r, nil
+}
+
+// Link creates a link to the encoded Plant UML in the given format.
+func (r *Renderer) Link(format string, encoded []byte) string {
+	return fmt.Sprintf("%s/%s/%s", r.server, format, string(encoded))
+}
+
+// linkLimits are common limits of the length of links, from the longest.
+var linkLimits = []struct {
+	length int
+	reason string
+}{
+	{length: 8192, reason: "which most servers reject"},
+	{length: 2048, reason: "which some browsers, servers and proxies reject"},
+}
+
+// LinkWarning returns a warning if the link is longer than common limits of the length of links,
+// or an empty string if it isn't.
+func LinkWarning(link string) string {
+	for _, limit := range linkLimits {
+		if len(link) > limit.length {
+			return fmt.Sprintf("the link is %d characters long, longer than %d characters %s", len(link), limit.length, limit.reason)
+		}
+	}
+
+	return ""
+}
+
+// Render fetches the encoded Plant UML in the given format and writes it to out.
+// When the server reports a diagram error, a *DiagramError is returned.
+//
+// Requests which fail because the server is temporarily unavailable are retried,
+// and fail fast with ErrCircuitOpen while the circuit breaker is open.
+// The request and the backoff between retries are cancelled with the context.
+func (r *Renderer) Render(ctx context.Context, out io.Writer, format string, encoded []byte) error {
+	if err := r.breaker.allow(); err != nil {
+		return err
+	}
+
+	response, err := r.fetch(ctx, format, encoded)
+
+	// A cancelled request says nothing about the server.
+	if ctx.Err() == nil {
+		var unavailableErr *unavailableError
+		r.breaker.record(errors.As(err, &unavailableErr))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if _, err = io.Copy(out, response.Body); err != nil {
+		return fmt.Errorf("couldn't write to output: %w", err)
+	}
+
+	return nil
+}
+
+// fetch sends the request and retries it with a backoff while the server is unavailable,
+// the response is returned when the status is OK.
+func (r *Renderer) fetch(ctx context.Context, format string, encoded []byte) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		response, err := r.send(ctx, format, encoded)
+
+		var unavailableErr *unavailableError
+		if err == nil || !errors.As(err, &unavailableErr) || retry >= r.retries {
+			return response, err
+		}
+
+		delay := unavailableErr.retryAfter
+		if delay > maxRetryAfter {
+			return nil, err
+		}
+
+		if delay == 0 {
+			delay = backoff(retry, r.minBackoff, r.maxBackoff)
+		}
+
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send sends one request, errors for which the request can be retried are returned as an *unavailableError.
+func (r *Renderer) send(ctx context.Context, format string, encoded []byte) (*http.Response, error) {
+	request, err := r.newRequest(ctx, format, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("can't create request: %w", err)
+	}
+
+	link := request.URL.String()
+
+	if err = r.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	response, err := r.client.Do(request)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if response != nil {
+			response.Body.Close()
+		}
+
+		return nil, ctxErr
+	}
+
+	if err != nil {
+		err = fmt.Errorf("can't fetch output: %w", err)
+
+		if isRetryableError(err) {
+			return nil, &unavailableError{err: err}
+		}
+
+		return nil, err
+	}
+
+	if err = diagramError(response); err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+
+	response.Body.Close()
+
+	err = fmt.Errorf("wrong status code %s, when fetching link: %s", response.Status, link)
+
+	if isRetryableStatus(response.StatusCode) {
+		retryAfter := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+		return nil, &unavailableError{err: err, retryAfter: retryAfter}
+	}
+
+	return nil, err
+}
+
+// Validate renders the encoded Plant UML and discards the output,
+// it's used to find diagram errors.
+func (r *Renderer) Validate(ctx context.Context, encoded []byte) error {
+	return r.Render(ctx, io.Discard, "svg", encoded)
+}
+
+// newRequest creates a GET request for the link to the encoded Plant UML,
+// or a POST request with the decoded Plant UML when the method or the length of the link requires it.
+func (r *Renderer) newRequest(ctx context.Context, format string, encoded []byte) (*http.Request, error) {
+	link := r.Link(format, encoded)
+
+	var request *http.Request
+
+	if r.method == MethodPost || (r.method == MethodAuto && len(link) > r.maxLinkLength) {
+		content, err := decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		if request, err = http.NewRequestWithContext(ctx, http.MethodPost, r.server+"/"+format, bytes.NewReader(content)); err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		var err error
+		if request, err = http.NewRequestWithContext(ctx, http.MethodGet, link, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, values := range r.header {
+		request.Header[name] = values
+	}
+
+	return request, nil
+}
+
+// decode decodes and inflates the encoded Plant UML.
+func decode(encoded []byte) ([]byte, error) {
+	compressed, err := gopuml.Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode the Plant UML: %w", err)
+	}
+
+	content, err := gopuml.Inflate(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't inflate the Plant UML: %w", err)
+	}
+
+	return content, nil
+}
+
+func diagramError(response *http.Response) error {
+	message := response.Header.Get(HeaderDiagramError)
+	if message == "" {
+		return nil
+	}
+
+	line, err := strconv.Atoi(response.Header.Get(HeaderDiagramErrorLine))
+	if err != nil {
+		line = 0
+	}
+
+	return &DiagramError{
+		StatusCode: response.StatusCode,
+		Message:    message,
+		Line:       line,
+	}
+}
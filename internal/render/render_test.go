@@ -0,0 +1,118 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/internal/render"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+const (
+	validDiagram   = "@startuml\nAlice -> Bob\n@enduml\n"
+	invalidDiagram = "@startuml\nAlice -> invalid\n@enduml\n"
+)
+
+// encode returns the Plant UML text encoding of the diagram.
+func encode(t *testing.T, diagram string) []byte {
+	t.Helper()
+
+	compressed, err := gopuml.Deflate([]byte(diagram))
+	require.Nil(t, err)
+
+	return gopuml.Encode(compressed)
+}
+
+func Test_Render(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetFixture("txt", []byte("rendered"))
+	server.SetDiagramError("invalid", 2, "Syntax Error?")
+
+	valid := encode(t, validDiagram)
+
+	renderer := render.New(server.URL + "/")
+	assert.Equal(t, server.URL+"/txt/"+string(valid), renderer.Link("txt", valid))
+
+	var out bytes.Buffer
+
+	err := renderer.Render(context.Background(), &out, "txt", valid)
+	require.Nil(t, err)
+	assert.Equal(t, "rendered", out.String())
+
+	err = renderer.Render(context.Background(), &out, "txt", encode(t, invalidDiagram))
+	diagramErr, ok := render.AsDiagramError(err)
+	require.True(t, ok)
+	assert.Equal(t, http.StatusBadRequest, diagramErr.StatusCode)
+	assert.Equal(t, "Syntax Error?", diagramErr.Message)
+	assert.Equal(t, 2, diagramErr.Line)
+	assert.Equal(t, "line 2: Syntax Error?", diagramErr.Error())
+
+	server.FailNext(plantumltest.Failure{StatusCode: http.StatusInternalServerError})
+
+	err = renderer.Render(context.Background(), &out, "txt", valid)
+	require.NotNil(t, err)
+
+	_, ok = render.AsDiagramError(err)
+	assert.False(t, ok)
+}
+
+func Test_RenderWithPost(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	encoded := encode(t, validDiagram)
+
+	var out bytes.Buffer
+
+	renderer, err := render.NewWithOptions(server.URL, render.ClientOptions{Method: render.MethodPost})
+	require.Nil(t, err)
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", encoded))
+	assert.Equal(t, string(plantumltest.Render("svg", []byte(validDiagram))), out.String())
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPost, requests[0].Method)
+	assert.Equal(t, "svg", requests[0].Format)
+	assert.Equal(t, validDiagram, requests[0].Source)
+
+	out.Reset()
+
+	renderer, err = render.NewWithOptions(server.URL, render.ClientOptions{MaxLinkLength: len(server.URL) + 5})
+	require.Nil(t, err)
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", encoded))
+
+	requests = server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPost, requests[1].Method)
+
+	out.Reset()
+
+	require.Nil(t, render.New(server.URL).Render(context.Background(), &out, "svg", encoded))
+	assert.Equal(t, string(plantumltest.Render("svg", []byte(validDiagram))), out.String())
+
+	requests = server.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, http.MethodGet, requests[2].Method)
+	assert.Equal(t, validDiagram, requests[2].Source)
+
+	_, err = render.NewWithOptions(server.URL, render.ClientOptions{Method: "put"})
+	assert.NotNil(t, err)
+}
+
+func Test_LinkWarning(t *testing.T) {
+	assert.Empty(t, render.LinkWarning(strings.Repeat("a", 2048)))
+	assert.Equal(t,
+		"the link is 2049 characters long, longer than 2048 characters which some browsers, servers and proxies reject",
+		render.LinkWarning(strings.Repeat("a", 2049)),
+	)
+	assert.Contains(t, render.LinkWarning(strings.Repeat("a", 8193)), "which most servers reject")
+}
@@ -0,0 +1,258 @@
+package render
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMinBackoff is the backoff before the first retry, it's doubled for every retry.
+	DefaultMinBackoff = 250 * time.Millisecond
+	// DefaultMaxBackoff is the longest backoff between retries.
+	DefaultMaxBackoff = 10 * time.Second
+	// DefaultBreakerCooldown is the time the circuit breaker stays open.
+	DefaultBreakerCooldown = 30 * time.Second
+
+	// maxRetryAfter is the longest Retry-After to wait for, longer waits aren't retried.
+	maxRetryAfter = time.Minute
+)
+
+// ErrCircuitOpen is returned without sending a request,
+// when the Plant UML server has failed too many times in a row.
+var ErrCircuitOpen = errors.New("the Plant UML server failed too many times in a row, failing fast")
+
+// unavailableError is an error which may be temporary, like a server error or a network error.
+type unavailableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (err *unavailableError) Error() string {
+	return err.err.Error()
+}
+
+func (err *unavailableError) Unwrap() error {
+	return err.err
+}
+
+// isRetryableStatus reports if the status code means that the server is temporarily unavailable.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// isRetryableError reports if the error of a request means that the network or the server is temporarily unavailable,
+// like a refused or reset connection or a timeout. TLS and certificate errors are configuration errors, which aren't retried.
+func isRetryableError(err error) bool {
+	var (
+		verificationErr     *tls.CertificateVerificationError
+		recordHeaderErr     tls.RecordHeaderError
+		alertErr            tls.AlertError
+		unknownAuthorityErr x509.UnknownAuthorityError
+		hostnameErr         x509.HostnameError
+		invalidErr          x509.CertificateInvalidError
+		dnsErr              *net.DNSError
+		opErr               *net.OpError
+		netErr              net.Error
+	)
+
+	switch {
+	case errors.As(err, &verificationErr), errors.As(err, &recordHeaderErr), errors.As(err, &alertErr),
+		errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return false
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return true
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	case errors.As(err, &opErr):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+
+	return false
+}
+
+// parseRetryAfter parses the Retry-After header, which is either seconds or a date,
+// it returns zero when the header isn't set or can't be parsed.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// backoff returns the exponential backoff before the retry, starting at 0,
+// with a random jitter of up to half the backoff.
+func backoff(retry int, minBackoff, maxBackoff time.Duration) time.Duration {
+	delay := maxBackoff
+
+	if retry < 32 && minBackoff<<retry < maxBackoff { // nolint: gomnd
+		delay = minBackoff << retry
+	}
+
+	half := int64(delay / 2) // nolint: gomnd
+	if half <= 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half+1)) // nolint: gosec
+}
+
+// ParseRate parses a rate of requests like "10/s", "100/m" or "1000/h" into requests per second,
+// a rate without a unit is per second.
+func ParseRate(value string) (float64, error) {
+	count, unit, _ := strings.Cut(value, "/")
+
+	requests, err := strconv.ParseFloat(count, 64)
+	if err != nil || requests <= 0 {
+		return 0, fmt.Errorf("invalid rate: [%s], expected a positive number of requests like 10/s", value)
+	}
+
+	switch unit {
+	case "", "s":
+		return requests, nil
+	case "m":
+		return requests / time.Minute.Seconds(), nil
+	case "h":
+		return requests / time.Hour.Seconds(), nil
+	}
+
+	return 0, fmt.Errorf("invalid rate: [%s], the unit must be s, m or h", value)
+}
+
+// limiter spaces the requests evenly to limit the rate of requests.
+type limiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newLimiter creates a limiter for the requests per second, a nil limiter doesn't limit the rate.
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait blocks until the next request may be sent, or the context is done.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+
+	l.mutex.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// sleep blocks for the duration, or until the context is done.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// breaker is a circuit breaker which opens after a number of failures in a row,
+// while it's open, requests fail fast. After the cooldown, a request is let through
+// and the breaker opens again if it fails.
+type breaker struct {
+	mutex     sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// newBreaker creates a breaker, a nil breaker never opens.
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns ErrCircuitOpen while the breaker is open.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures >= b.threshold && time.Now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+
+	return nil
+}
+
+// record records if the server was unavailable for a request.
+func (b *breaker) record(unavailable bool) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !unavailable {
+		b.failures = 0
+		return
+	}
+
+	if b.failures++; b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,209 @@
+package render_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/render"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+// flakyServer fails the first requests with the status code and header.
+func flakyServer(t *testing.T, failures int, statusCode int, header http.Header) *plantumltest.Server {
+	t.Helper()
+
+	server := plantumltest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.SetFixture("svg", []byte("rendered"))
+
+	for idx := 0; idx < failures; idx++ {
+		server.FailNext(plantumltest.Failure{StatusCode: statusCode, Header: header})
+	}
+
+	return server
+}
+
+func Test_RenderWithRetries(t *testing.T) {
+	opts := render.ClientOptions{Retries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+	server := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+
+	renderer, err := render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+	assert.Equal(t, "rendered", out.String())
+	assert.Len(t, server.Requests(), 3)
+
+	server = flakyServer(t, 5, http.StatusBadGateway, nil)
+
+	renderer, err = render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+	assert.Len(t, server.Requests(), 4)
+
+	server = flakyServer(t, 5, http.StatusNotFound, nil)
+
+	renderer, err = render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+	assert.Len(t, server.Requests(), 1)
+
+	header := http.Header{render.HeaderDiagramError: []string{"Syntax Error?"}}
+	server = flakyServer(t, 5, http.StatusServiceUnavailable, header)
+
+	renderer, err = render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+
+	_, ok := render.AsDiagramError(renderer.Render(context.Background(), &out, "svg", encode(t, invalidDiagram)))
+	assert.True(t, ok)
+	assert.Len(t, server.Requests(), 1)
+}
+
+func Test_RenderWithNetworkErrors(t *testing.T) {
+	opts := render.ClientOptions{Retries: 2, MinBackoff: time.Millisecond, BreakerThreshold: 1}
+
+	closed := plantumltest.NewServer()
+	closed.Close()
+
+	renderer, err := render.NewWithOptions(closed.URL, opts)
+	require.Nil(t, err)
+
+	// A refused connection is retried and counts as a failure of the server.
+	assert.NotNil(t, renderer.Render(context.Background(), &bytes.Buffer{}, "svg", encode(t, validDiagram)))
+	assert.ErrorIs(t, renderer.Render(context.Background(), &bytes.Buffer{}, "svg", encode(t, validDiagram)), render.ErrCircuitOpen)
+
+	server := plantumltest.NewTLSServer()
+	defer server.Close()
+
+	opts.MinBackoff = time.Minute
+
+	renderer, err = render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// An untrusted certificate isn't retried and doesn't open the circuit breaker.
+	err = renderer.Render(ctx, &bytes.Buffer{}, "svg", encode(t, validDiagram))
+	require.NotNil(t, err)
+	assert.NotErrorIs(t, err, context.DeadlineExceeded)
+
+	var certErr *tls.CertificateVerificationError
+	assert.ErrorAs(t, err, &certErr)
+
+	err = renderer.Render(ctx, &bytes.Buffer{}, "svg", encode(t, validDiagram))
+	assert.NotErrorIs(t, err, render.ErrCircuitOpen)
+	assert.NotErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_RenderWithRetryAfter(t *testing.T) {
+	server := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"1"}})
+
+	renderer, err := render.NewWithOptions(server.URL, render.ClientOptions{Retries: 1, MinBackoff: time.Millisecond})
+	require.Nil(t, err)
+
+	start := time.Now()
+
+	require.Nil(t, renderer.Render(context.Background(), &bytes.Buffer{}, "svg", encode(t, validDiagram)))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Len(t, server.Requests(), 2)
+
+	server = flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"3600"}})
+
+	renderer, err = render.NewWithOptions(server.URL, render.ClientOptions{Retries: 1})
+	require.Nil(t, err)
+	assert.NotNil(t, renderer.Render(context.Background(), &bytes.Buffer{}, "svg", encode(t, validDiagram)))
+	assert.Len(t, server.Requests(), 1)
+}
+
+func Test_RenderWithCircuitBreaker(t *testing.T) {
+	server := flakyServer(t, 2, http.StatusInternalServerError, nil)
+
+	opts := render.ClientOptions{BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond}
+
+	renderer, err := render.NewWithOptions(server.URL, opts)
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+
+	err = renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram))
+	assert.True(t, errors.Is(err, render.ErrCircuitOpen))
+	assert.Len(t, server.Requests(), 2)
+
+	time.Sleep(60 * time.Millisecond)
+
+	require.Nil(t, renderer.Render(context.Background(), &out, "svg", encode(t, validDiagram)))
+	assert.Len(t, server.Requests(), 3)
+}
+
+func Test_RenderWithRate(t *testing.T) {
+	server := flakyServer(t, 0, http.StatusOK, nil)
+
+	renderer, err := render.NewWithOptions(server.URL, render.ClientOptions{Rate: 20})
+	require.Nil(t, err)
+
+	start := time.Now()
+
+	for idx := 0; idx < 5; idx++ {
+		require.Nil(t, renderer.Render(context.Background(), &bytes.Buffer{}, "svg", encode(t, validDiagram)))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.Len(t, server.Requests(), 5)
+}
+
+func Test_ParseRate(t *testing.T) {
+	for value, expected := range map[string]float64{"10/s": 10, "10": 10, "120/m": 2, "3600/h": 1, "0.5/s": 0.5} {
+		rate, err := render.ParseRate(value)
+		require.Nil(t, err, value)
+		assert.Equal(t, expected, rate, value)
+	}
+
+	for _, value := range []string{"", "0/s", "-1/s", "ten/s", "10/d"} {
+		_, err := render.ParseRate(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func Test_RenderCancelled(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetLatency(time.Minute)
+
+	renderer, err := render.NewWithOptions(server.URL, render.ClientOptions{Retries: 3, BreakerThreshold: 1})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = renderer.Render(ctx, &bytes.Buffer{}, "svg", encode(t, validDiagram))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	failing := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+
+	renderer, err = render.NewWithOptions(failing.URL, render.ClientOptions{Retries: 3, MinBackoff: time.Minute})
+	require.Nil(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = renderer.Render(ctx, &bytes.Buffer{}, "svg", encode(t, validDiagram))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Len(t, failing.Requests(), 1)
+}
@@ -0,0 +1,292 @@
+// Package source loads Plant UML source files.
+//
+// Local files included with !include or !include_once are inlined,
+// since a Plant UML server can't read them, while the origin of every
+// inlined line is kept, so errors can be reported on the original file and line.
+//
+// Files generated from other sources can set the origin of the following lines with a comment,
+// where the path is relative to the file:
+//
+//	' gopuml:origin=server.go:42
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Origin is the file and line a line of a Source originates from.
+type Origin struct {
+	Filepath string
+	// Line is the line in the file, starting at 1.
+	Line int
+}
+
+// Source is Plant UML source where local includes have been inlined.
+type Source struct {
+	Content []byte
+	// Origins has the origin of every line in the content.
+	Origins []Origin
+}
+
+// New creates a Source from content without inlining any includes,
+// the name is used as the file of the origins.
+func New(name string, content []byte) Source {
+	var src Source
+
+	src.Content = content
+	src.Origins = lineOrigins(name, splitLines(content))
+
+	return src
+}
+
+// Load reads the file and inlines all local includes,
+// the content is kept as is when the file has no local includes.
+func Load(path string) (Source, error) {
+	return load(path, os.ReadFile, isFile)
+}
+
+// LoadWith is like Load, but reads the file and the included files with the read function,
+// like a function which reads the content staged in git. Only the includes which can be read are inlined.
+func LoadWith(path string, read func(path string) ([]byte, error)) (Source, error) {
+	return load(path, read, func(path string) bool {
+		_, err := read(path)
+		return err == nil
+	})
+}
+
+// load reads the file and inlines the includes which exist according to the exists function.
+func load(path string, read func(path string) ([]byte, error), exists func(path string) bool) (Source, error) {
+	content, err := read(path)
+	if err != nil {
+		return Source{}, err
+	}
+
+	if len(includes(filepath.Dir(path), content, exists)) == 0 {
+		return New(path, content), nil
+	}
+
+	l := loader{read: read, exists: exists, included: make(map[string]bool)}
+
+	var buffer bytes.Buffer
+	if err = l.load(&buffer, path, false); err != nil {
+		return Source{}, err
+	}
+
+	return Source{Content: buffer.Bytes(), Origins: l.origins}, nil
+}
+
+// Origin returns the origin of the line in the content, starting at 1.
+// If the line is out of range, the origin of the closest line is returned.
+func (src Source) Origin(line int) Origin {
+	if len(src.Origins) == 0 {
+		return Origin{Line: line}
+	}
+
+	if line < 1 {
+		line = 1
+	}
+
+	if line > len(src.Origins) {
+		line = len(src.Origins)
+	}
+
+	return src.Origins[line-1]
+}
+
+var (
+	includeRegexp  = regexp.MustCompile(`^\s*!include(_once)?\s+(.+?)\s*$`)
+	startEndRegexp = regexp.MustCompile(`^\s*@(start|end)\w+`)
+	originRegexp   = regexp.MustCompile(`^\s*'\s*gopuml:origin=(.+):(\d+)\s*$`)
+)
+
+// OriginComment formats a comment which sets the origin of the following lines.
+func OriginComment(origin Origin) string {
+	return fmt.Sprintf("' gopuml:origin=%s:%d", filepath.ToSlash(origin.Filepath), origin.Line)
+}
+
+// lineOrigins returns the origins of the lines in the file,
+// which are the lines in the file itself unless set by an origin comment.
+func lineOrigins(path string, lines []string) []Origin {
+	origins := make([]Origin, 0, len(lines))
+
+	var current *Origin
+
+	for idx, line := range lines {
+		if matches := originRegexp.FindStringSubmatch(line); matches != nil {
+			originPath := filepath.FromSlash(matches[1])
+			if !filepath.IsAbs(originPath) {
+				originPath = filepath.Join(filepath.Dir(path), originPath)
+			}
+
+			originLine, _ := strconv.Atoi(matches[2])
+			current = &Origin{Filepath: originPath, Line: originLine}
+
+			origins = append(origins, Origin{Filepath: path, Line: idx + 1})
+
+			continue
+		}
+
+		if current == nil {
+			origins = append(origins, Origin{Filepath: path, Line: idx + 1})
+			continue
+		}
+
+		origins = append(origins, *current)
+		current.Line++
+	}
+
+	return origins
+}
+
+// Includes returns the local files included by the content, relative to dir.
+// Includes of URLs, the standard library and files that don't exist are ignored.
+func Includes(dir string, content []byte) []string {
+	return includes(dir, content, isFile)
+}
+
+func includes(dir string, content []byte, exists func(path string) bool) []string {
+	var result []string
+
+	for _, line := range splitLines(content) {
+		if path, _, ok := parseInclude(dir, line, exists); ok {
+			result = append(result, path)
+		}
+	}
+
+	return result
+}
+
+// isFile reports if the path is a file in the file system.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func parseInclude(dir, line string, exists func(path string) bool) (path string, once, ok bool) {
+	matches := includeRegexp.FindStringSubmatch(line)
+	if matches == nil {
+		return "", false, false
+	}
+
+	target := strings.Trim(matches[2], `"`)
+
+	if strings.HasPrefix(target, "<") || strings.Contains(target, "://") {
+		return "", false, false
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+
+	if !exists(target) {
+		return "", false, false
+	}
+
+	return target, matches[1] != "", true
+}
+
+type loader struct {
+	read     func(path string) ([]byte, error)
+	exists   func(path string) bool
+	origins  []Origin
+	included map[string]bool
+	stack    []string
+}
+
+func (l *loader) load(buffer *bytes.Buffer, path string, isIncluded bool) error {
+	for _, p := range l.stack {
+		if p == path {
+			return fmt.Errorf("include cycle detected: %s", strings.Join(append(l.stack, path), " -> "))
+		}
+	}
+
+	l.stack = append(l.stack, path)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	l.included[path] = true
+
+	content, err := l.read(path)
+	if err != nil {
+		return err
+	}
+
+	lines := splitLines(content)
+	origins := lineOrigins(path, lines)
+
+	for idx, line := range lines {
+		if isIncluded && startEndRegexp.MatchString(line) {
+			continue
+		}
+
+		if includePath, once, ok := parseInclude(filepath.Dir(path), line, l.exists); ok {
+			if once && l.included[includePath] {
+				continue
+			}
+
+			if err = l.load(buffer, includePath, true); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		buffer.WriteString(line)
+		buffer.WriteByte('\n')
+
+		l.origins = append(l.origins, origins[idx])
+	}
+
+	return nil
+}
+
+func splitLines(content []byte) []string {
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines
+}
+
+var nameRegexp = regexp.MustCompile(`(?m)^\s*@start[a-z]+(?:\(id=([^)]+)\)|[ \t]+(?:"([^"]+)"|(.+?)))[ \t]*\r?$`)
+
+// DiagramName returns the name after @startuml of the first diagram in the content,
+// or an empty string if the diagram has no name.
+func DiagramName(content []byte) string {
+	matches := nameRegexp.FindSubmatch(content)
+	if matches == nil {
+		return ""
+	}
+
+	for _, match := range matches[1:] {
+		if len(match) > 0 {
+			return string(match)
+		}
+	}
+
+	return ""
+}
+
+var titleRegexp = regexp.MustCompile(`(?mi)^[ \t]*title[ \t]+(.+?)[ \t]*\r?$`)
+
+// DiagramTitle returns the single line title of the first diagram in the content,
+// or an empty string if the diagram has no such title.
+func DiagramTitle(content []byte) string {
+	matches := titleRegexp.FindSubmatch(content)
+	if matches == nil {
+		return ""
+	}
+
+	return strings.Trim(string(matches[1]), `"`)
+}
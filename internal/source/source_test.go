@@ -0,0 +1,156 @@
+package source_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml/internal/source"
+)
+
+func Test_Load(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "skin.puml"), "@startuml\nskinparam monochrome true\n@enduml\n")
+	writeFile(t, filepath.Join(dir, "common", "actors.puml"), "actor Bob\n!include_once ../skin.puml\n")
+
+	mainPath := filepath.Join(dir, "main.puml")
+	writeFile(t, mainPath, `@startuml Main
+!include skin.puml
+!include common/actors.puml
+!include <C4/C4_Container>
+!include https://example.com/remote.puml
+Bob -> Alice : hello
+@enduml`)
+
+	src, err := source.Load(mainPath)
+	require.Nil(t, err)
+
+	expectedContent := `@startuml Main
+skinparam monochrome true
+actor Bob
+!include <C4/C4_Container>
+!include https://example.com/remote.puml
+Bob -> Alice : hello
+@enduml
+`
+	assert.Equal(t, expectedContent, string(src.Content))
+
+	assert.Equal(t, source.Origin{Filepath: mainPath, Line: 1}, src.Origin(1))
+	assert.Equal(t, source.Origin{Filepath: filepath.Join(dir, "skin.puml"), Line: 2}, src.Origin(2))
+	assert.Equal(t, source.Origin{Filepath: filepath.Join(dir, "common", "actors.puml"), Line: 1}, src.Origin(3))
+	assert.Equal(t, source.Origin{Filepath: mainPath, Line: 6}, src.Origin(6))
+	assert.Equal(t, source.Origin{Filepath: mainPath, Line: 7}, src.Origin(100))
+}
+
+func Test_LoadWithoutIncludes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.puml")
+	content := "@startuml\r\nBob -> Alice : hello\r\n@enduml"
+
+	writeFile(t, path, content)
+
+	src, err := source.Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, content, string(src.Content))
+	assert.Equal(t, source.Origin{Filepath: path, Line: 2}, src.Origin(2))
+}
+
+func Test_LoadWith(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.puml")
+	skinPath := filepath.Join(dir, "skin.puml")
+	otherPath := filepath.Join(dir, "other.puml")
+
+	writeFile(t, mainPath, "@startuml\nBob -> Alice\n@enduml\n")
+	writeFile(t, otherPath, "skinparam shadowing false\n")
+
+	// The included files are found and read with the read function, the skin only exists in it
+	// and the other file only exists on disk.
+	staged := map[string]string{
+		mainPath: "@startuml\n!include skin.puml\n!include other.puml\nAlice -> Bob\n@enduml\n",
+		skinPath: "skinparam monochrome false\n",
+	}
+
+	src, err := source.LoadWith(mainPath, func(path string) ([]byte, error) {
+		content, ok := staged[path]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+
+		return []byte(content), nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "@startuml\nskinparam monochrome false\n!include other.puml\nAlice -> Bob\n@enduml\n", string(src.Content))
+}
+
+func Test_LoadWithIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "a.puml"), "!include b.puml\n")
+	writeFile(t, filepath.Join(dir, "b.puml"), "!include a.puml\n")
+
+	_, err := source.Load(filepath.Join(dir, "a.puml"))
+	assert.NotNil(t, err)
+}
+
+func Test_LoadWithOriginComment(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "diagrams", "flow.puml")
+
+	writeFile(t, path, "' gopuml:origin=../server/server.go:12\n@startuml\nBob -> Alice : hello\n@enduml\n")
+
+	src, err := source.Load(path)
+	require.Nil(t, err)
+
+	goFile := filepath.Join(dir, "server", "server.go")
+
+	assert.Equal(t, source.Origin{Filepath: path, Line: 1}, src.Origin(1))
+	assert.Equal(t, source.Origin{Filepath: goFile, Line: 12}, src.Origin(2))
+	assert.Equal(t, source.Origin{Filepath: goFile, Line: 13}, src.Origin(3))
+
+	assert.Equal(t, "' gopuml:origin=../server/server.go:12", source.OriginComment(source.Origin{Filepath: "../server/server.go", Line: 12}))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	require.Nil(t, err)
+
+	err = os.WriteFile(path, []byte(content), 0600)
+	require.Nil(t, err)
+}
+
+func Test_DiagramName(t *testing.T) {
+	testcases := []struct {
+		content      string
+		expectedName string
+	}{
+		{content: "@startuml Example\nBob -> Alice : hello\n@enduml", expectedName: "Example"},
+		{content: "' comment\n@startuml \"An Example\"\r\n@enduml", expectedName: "An Example"},
+		{content: "@startuml(id=example)\n@enduml", expectedName: "example"},
+		{content: "@startuml\nBob -> Alice : hello\n@enduml", expectedName: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expectedName, source.DiagramName([]byte(tc.content)), tc.content)
+	}
+}
+
+func Test_DiagramTitle(t *testing.T) {
+	testcases := []struct {
+		content       string
+		expectedTitle string
+	}{
+		{content: "@startuml\ntitle Request Flow\nBob -> Alice : hello\n@enduml", expectedTitle: "Request Flow"},
+		{content: "@startuml\n  TITLE \"Quoted Title\"\r\n@enduml", expectedTitle: "Quoted Title"},
+		{content: "@startuml\ntitle\n  Multi Line\nend title\n@enduml", expectedTitle: ""},
+		{content: "@startuml Example\nBob -> Alice : hello\n@enduml", expectedTitle: ""},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expectedTitle, source.DiagramTitle([]byte(tc.content)), tc.content)
+	}
+}
@@ -0,0 +1,291 @@
+// Package plantumltest provides a fake Plant UML server for tests,
+// which renders diagrams without network access.
+//
+// The server supports links formatted like "<server_url>/<format>/<plant_uml_text_encoding>"
+// and POST requests with the Plant UML as the body to "<server_url>/<format>".
+// Diagrams are rendered to deterministic fixture output, and errors, latency
+// and diagram errors can be injected:
+//
+//	server := plantumltest.NewServer()
+//	defer server.Close()
+//
+//	server.SetDiagramError("invalid", 2, "Syntax Error?")
+//	server.FailNext(plantumltest.Failure{StatusCode: http.StatusServiceUnavailable})
+package plantumltest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lonnblad/gopuml"
+)
+
+const (
+	// HeaderDiagramError is the header used by the Plant UML server to report a diagram error.
+	HeaderDiagramError = "X-PlantUML-Diagram-Error"
+	// HeaderDiagramErrorLine is the header used by the Plant UML server to report the line of a diagram error.
+	HeaderDiagramErrorLine = "X-PlantUML-Diagram-Error-Line"
+)
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Format string
+	// Source is the decoded Plant UML.
+	Source string
+	Header http.Header
+}
+
+// Failure is a response sent instead of the rendered diagram.
+type Failure struct {
+	StatusCode int
+	// Header is added to the response, for example a Retry-After header.
+	Header http.Header
+}
+
+type diagramError struct {
+	contains string
+	line     int
+	message  string
+}
+
+// Server is a fake Plant UML server.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	fixtures      map[string][]byte
+	diagramErrors []diagramError
+	failures      []Failure
+	latency       time.Duration
+	requests      []Request
+}
+
+// NewServer starts a fake Plant UML server, which should be closed when the test is done.
+func NewServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
+
+// NewTLSServer starts a fake Plant UML server using TLS, the client of the server trusts its certificate.
+func NewTLSServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewTLSServer(s)
+
+	return s
+}
+
+func newServer() *Server {
+	return &Server{fixtures: make(map[string][]byte)}
+}
+
+// SetFixture sets the output of every diagram rendered in the format, instead of the output of Render.
+func (s *Server) SetFixture(format string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fixtures[format] = content
+}
+
+// SetDiagramError makes the server report a diagram error for the diagrams which contain the text,
+// with the diagram error headers and the status Bad Request. A line of 0 leaves out the line header.
+func (s *Server) SetDiagramError(contains string, line int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.diagramErrors = append(s.diagramErrors, diagramError{contains: contains, line: line, message: message})
+}
+
+// FailNext makes the server respond to the next requests with the failures, one failure per request.
+func (s *Server) FailNext(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failures...)
+}
+
+// SetLatency delays every response, the delay ends early when the request is cancelled.
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = latency
+}
+
+// Requests returns the requests received by the server, from the first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// ServeHTTP renders the diagram of the request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	format, source, err := decodeRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: req.Method, Format: format, Source: string(source), Header: req.Header.Clone()})
+	latency := s.latency
+
+	var failure *Failure
+
+	if len(s.failures) > 0 {
+		failure = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+
+	diagramErr, hasDiagramErr := s.findDiagramError(source)
+
+	fixture, hasFixture := s.fixtures[format]
+	s.mu.Unlock()
+
+	if !wait(req.Context(), latency) {
+		return
+	}
+
+	switch {
+	case failure != nil:
+		for name, values := range failure.Header {
+			w.Header()[name] = values
+		}
+
+		w.WriteHeader(failure.StatusCode)
+	case hasDiagramErr:
+		w.Header().Set(HeaderDiagramError, diagramErr.message)
+
+		if diagramErr.line > 0 {
+			w.Header().Set(HeaderDiagramErrorLine, strconv.Itoa(diagramErr.line))
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		if !hasFixture {
+			fixture = Render(format, source)
+		}
+
+		w.Header().Set("Content-Type", contentType(format))
+		w.Write(fixture) // nolint: errcheck
+	}
+}
+
+// findDiagramError returns the first diagram error set for the source, s.mu must be held.
+func (s *Server) findDiagramError(source []byte) (diagramError, bool) {
+	for _, diagramErr := range s.diagramErrors {
+		if bytes.Contains(source, []byte(diagramErr.contains)) {
+			return diagramErr, true
+		}
+	}
+
+	return diagramError{}, false
+}
+
+// decodeRequest returns the format and the decoded Plant UML of a GET or a POST request.
+func decodeRequest(req *http.Request) (string, []byte, error) {
+	path := strings.TrimPrefix(req.URL.Path, "/")
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		format, encoded, ok := strings.Cut(path, "/")
+		if !ok || format == "" || encoded == "" {
+			return "", nil, fmt.Errorf("the path isn't formatted like /<format>/<encoded>: %s", req.URL.Path)
+		}
+
+		compressed, err := gopuml.Decode([]byte(encoded))
+		if err != nil {
+			return "", nil, fmt.Errorf("couldn't decode the Plant UML: %w", err)
+		}
+
+		source, err := gopuml.Inflate(compressed)
+		if err != nil {
+			return "", nil, fmt.Errorf("couldn't inflate the Plant UML: %w", err)
+		}
+
+		return format, source, nil
+	case http.MethodPost:
+		if path == "" || strings.Contains(path, "/") {
+			return "", nil, fmt.Errorf("the path isn't formatted like /<format>: %s", req.URL.Path)
+		}
+
+		source, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", nil, fmt.Errorf("couldn't read the Plant UML: %w", err)
+		}
+
+		return path, source, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported method: %s", req.Method)
+	}
+}
+
+// wait waits for the latency, it returns false if the context is done first.
+func wait(ctx context.Context, latency time.Duration) bool {
+	if latency <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Render returns the deterministic output of the fake server for the Plant UML in the format.
+// The txt format and unknown formats return the Plant UML, the svg format returns an SVG with the hash of the Plant UML
+// and the png format returns a single pixel PNG with a color taken from the hash of the Plant UML.
+func Render(format string, source []byte) []byte {
+	hash := sha256.Sum256(source)
+
+	switch format {
+	case "svg":
+		return []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="1" height="1"><desc>` +
+			hex.EncodeToString(hash[:]) + `</desc></svg>`)
+	case "png":
+		img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		img.Set(0, 0, color.RGBA{R: hash[0], G: hash[1], B: hash[2], A: 0xff})
+
+		var buf bytes.Buffer
+		png.Encode(&buf, img) // nolint: errcheck
+
+		return buf.Bytes()
+	default:
+		return append([]byte(nil), source...)
+	}
+}
+
+func contentType(format string) string {
+	switch format {
+	case "svg":
+		return "image/svg+xml"
+	case "png":
+		return "image/png"
+	case "txt":
+		return "text/plain; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,134 @@
+package plantumltest_test
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lonnblad/gopuml"
+	"github.com/lonnblad/gopuml/internal/render"
+	"github.com/lonnblad/gopuml/plantumltest"
+)
+
+const diagram = "@startuml Example\nBob -> Alice : hello\n@enduml\n"
+
+func Test_Server(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	encoded := encode(t, diagram)
+
+	assert.Equal(t, diagram, renderDiagram(t, render.New(server.URL), "txt", encoded))
+	assert.Equal(t, string(plantumltest.Render("svg", []byte(diagram))), renderDiagram(t, render.New(server.URL), "svg", encoded))
+
+	image, err := png.Decode(bytes.NewBufferString(renderDiagram(t, render.New(server.URL), "png", encoded)))
+	require.Nil(t, err)
+	assert.Equal(t, 1, image.Bounds().Dx())
+
+	post, err := render.NewWithOptions(server.URL, render.ClientOptions{Method: render.MethodPost})
+	require.Nil(t, err)
+	assert.Equal(t, diagram, renderDiagram(t, post, "txt", encoded))
+
+	server.SetFixture("svg", []byte("<svg/>"))
+	assert.Equal(t, "<svg/>", renderDiagram(t, render.New(server.URL), "svg", encoded))
+
+	requests := server.Requests()
+	require.Len(t, requests, 5)
+	assert.Equal(t, plantumltest.Request{Method: http.MethodPost, Format: "txt", Source: diagram, Header: requests[3].Header}, requests[3])
+	assert.Equal(t, http.MethodGet, requests[4].Method)
+	assert.Equal(t, "svg", requests[4].Format)
+	assert.Equal(t, diagram, requests[4].Source)
+
+	response, err := http.Get(server.URL + "/txt/not-encoded!")
+	require.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Len(t, server.Requests(), 5)
+}
+
+func Test_ServerDiagramError(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.SetDiagramError("invalid", 2, "Syntax Error?")
+
+	err := render.New(server.URL).Validate(context.Background(), encode(t, "@startuml\ninvalid\n@enduml\n"))
+	diagramErr, ok := render.AsDiagramError(err)
+	require.True(t, ok)
+	assert.Equal(t, &render.DiagramError{StatusCode: http.StatusBadRequest, Message: "Syntax Error?", Line: 2}, diagramErr)
+
+	assert.Nil(t, render.New(server.URL).Validate(context.Background(), encode(t, diagram)))
+}
+
+func Test_ServerFailuresAndLatency(t *testing.T) {
+	server := plantumltest.NewServer()
+	defer server.Close()
+
+	server.FailNext(
+		plantumltest.Failure{StatusCode: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"0"}}},
+		plantumltest.Failure{StatusCode: http.StatusNotFound},
+	)
+
+	renderer := render.New(server.URL)
+	encoded := encode(t, diagram)
+
+	var out bytes.Buffer
+
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "txt", encoded))
+	assert.NotNil(t, renderer.Render(context.Background(), &out, "txt", encoded))
+	assert.Empty(t, out.String())
+
+	out.Reset()
+	require.Nil(t, renderer.Render(context.Background(), &out, "txt", encoded))
+	assert.Equal(t, diagram, out.String())
+
+	server.SetLatency(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := renderer.Render(ctx, &out, "txt", encoded)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_TLSServer(t *testing.T) {
+	server := plantumltest.NewTLSServer()
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/txt/" + string(encode(t, diagram)))
+	require.Nil(t, err)
+
+	defer response.Body.Close()
+
+	var body bytes.Buffer
+
+	_, err = body.ReadFrom(response.Body)
+	require.Nil(t, err)
+	assert.Equal(t, diagram, body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", response.Header.Get("Content-Type"))
+}
+
+func renderDiagram(t *testing.T, renderer *render.Renderer, format string, encoded []byte) string {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	require.Nil(t, renderer.Render(context.Background(), &out, format, encoded))
+
+	return out.String()
+}
+
+func encode(t *testing.T, content string) []byte {
+	t.Helper()
+
+	compressed, err := gopuml.Deflate([]byte(content))
+	require.Nil(t, err)
+
+	return gopuml.Encode(compressed)
+}